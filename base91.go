@@ -6,6 +6,20 @@ import (
 	"unicode/utf8"
 )
 
+// IsBase91 reports whether s is a non-empty string of Base91 digits, i.e.
+// every byte lies in '!' (33) .. '{' (123)
+func IsBase91(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '!' || s[i] > '{' {
+			return false
+		}
+	}
+	return true
+}
+
 // ToDecimal transfers Base91 to decimal
 func ToDecimal(text string) (int, error) {
 	if text == "" {
@@ -13,15 +27,13 @@ func ToDecimal(text string) (int, error) {
 	}
 
 	text = strings.TrimLeft(text, "!")
+	if text != "" && !IsBase91(text) {
+		return 0, errors.New("invalid character in sequence")
+	}
 	result := 0
 
 	for i := 0; i < len(text); i++ {
-		char := text[i]
-		if char <= 0x20 || char >= 0x7c {
-			return 0, errors.New("invalid character in sequence")
-		}
-
-		value := int(char) - 33
+		value := int(text[i]) - 33
 		for j := 0; j < len(text)-1-i; j++ {
 			value *= 91
 		}
@@ -0,0 +1,24 @@
+package aprsutils
+
+import "testing"
+
+func TestIsBase91(t *testing.T) {
+	cases := map[string]bool{
+		"<*e7":   true,
+		"!!!!":   true,
+		"{":      true,
+		"":       false,
+		"ab c":   false, // space
+		"ab~":    false, // tilde
+		"ab\x7f": false, // DEL
+		"|":      false,
+	}
+	for s, want := range cases {
+		if got := IsBase91(s); got != want {
+			t.Errorf("IsBase91(%q) = %v, want %v", s, got, want)
+		}
+	}
+	if _, err := ToDecimal("ab~"); err == nil {
+		t.Error("ToDecimal accepted a non-Base91 character")
+	}
+}
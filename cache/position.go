@@ -0,0 +1,163 @@
+// Package cache keeps derived per-station state built from a stream of parsed
+// APRS packets, such as the last known position of every station for a live
+// map.
+package cache
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/APRSCN/aprsutils"
+	"github.com/APRSCN/aprsutils/parser"
+)
+
+// Entry is the latest known position of a station, object or item.
+type Entry struct {
+	Key    string    // station callsign (upper-cased), or ObjectPrefix + object/item name
+	Lat    float64   // decimal degrees
+	Lon    float64   // decimal degrees
+	Symbol []string  // [symbolCode, symbolTable], as in parser.Parsed
+	Time   time.Time // position time: packet timestamp, else receipt time
+	Packet parser.Parsed
+}
+
+// PositionCache tracks the latest position per station and expires entries
+// older than a TTL. It is safe for concurrent use.
+type PositionCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// NewPositionCache creates a cache whose entries expire ttl after their
+// position time.
+func NewPositionCache(ttl time.Duration) *PositionCache {
+	return &PositionCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]Entry),
+	}
+}
+
+// ObjectPrefix starts the cache key of every object and item, keeping them
+// apart from a station that uses the same name. No callsign contains ';'.
+const ObjectPrefix = ";"
+
+// Key returns the cache key for a packet: ObjectPrefix plus the object/item
+// name for objects and items (whose position belongs to the object, not the
+// sender), otherwise aprsutils.CallsignKey of the source callsign. Two
+// sources share a key exactly when aprsutils.SameStation reports them equal;
+// distinct SSIDs stay distinct, so a mobile at -9 and its home station are
+// tracked apart.
+func Key(p *parser.Parsed) string {
+	if p.PacketType.Has(parser.TypeObject|parser.TypeItem) && p.ObjectName != "" {
+		return ObjectPrefix + p.ObjectName
+	}
+	return aprsutils.CallsignKey(p.From)
+}
+
+// Update records the position carried by p, received now. See UpdateAt.
+func (c *PositionCache) Update(p parser.Parsed) bool {
+	return c.UpdateAt(p, c.now())
+}
+
+// UpdateAt records the position carried by p, received at the given time. The
+// packet's own timestamp is used when present (clamped to the receipt time to
+// ignore clock-skewed senders). Packets without a position are ignored, a
+// killed object/item removes its entry, and an update older than the stored
+// one is dropped so out-of-order delivery cannot move a station backwards. It
+// reports whether the cache changed.
+func (c *PositionCache) UpdateAt(p parser.Parsed, received time.Time) bool {
+	key := Key(&p)
+
+	if p.PacketType.Has(parser.TypeObject|parser.TypeItem) && !p.Alive {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.entries[key]; !ok {
+			return false
+		}
+		delete(c.entries, key)
+		return true
+	}
+
+	if !p.HasPosition {
+		return false
+	}
+
+	at := received
+	if p.Timestamp != 0 {
+		if ts := time.Unix(int64(p.Timestamp), 0); ts.Before(received) {
+			at = ts
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok && old.Time.After(at) {
+		return false
+	}
+	c.entries[key] = Entry{
+		Key:    key,
+		Lat:    p.Lat,
+		Lon:    p.Lon,
+		Symbol: p.Symbol,
+		Time:   at,
+		Packet: p,
+	}
+	return true
+}
+
+// Get returns the entry for key if present and not expired at now.
+func (c *PositionCache) Get(key string, now time.Time) (Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[key]
+	if !ok || c.expired(e, now) {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// Active returns the entries that have not expired at now, ordered by key.
+func (c *PositionCache) Active(now time.Time) []Entry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]Entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		if !c.expired(e, now) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Prune deletes the entries expired at now and returns how many were removed.
+func (c *PositionCache) Prune(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	for key, e := range c.entries {
+		if c.expired(e, now) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of stored entries, including expired ones not yet
+// pruned.
+func (c *PositionCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// expired reports whether e is older than the TTL at now.
+func (c *PositionCache) expired(e Entry, now time.Time) bool {
+	return now.Sub(e.Time) > c.ttl
+}
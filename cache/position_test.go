@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/APRSCN/aprsutils"
+	"github.com/APRSCN/aprsutils/parser"
+)
+
+// mustParse parses a packet for tests, failing on error.
+func mustParse(t *testing.T, raw string) parser.Parsed {
+	t.Helper()
+	p, err := parser.Parse(raw, parser.WithDisableToCallsignValidate())
+	if err != nil {
+		t.Fatalf("Parse(%q) error: %v", raw, err)
+	}
+	return p
+}
+
+func TestPositionCacheExpiry(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	c := NewPositionCache(30 * time.Minute)
+	c.now = func() time.Time { return now }
+
+	if !c.Update(mustParse(t, "N0CALL-9>APRS:!4903.50N/07201.75W>mobile")) {
+		t.Fatal("position update was not stored")
+	}
+	now = start.Add(20 * time.Minute)
+	c.Update(mustParse(t, "n0call>APRS:!4903.50N/07201.75W-home"))
+	c.Update(mustParse(t, "N0CALL-0>APRS:>status only"))
+
+	// -9 and the home station are distinct; "-0" folds into the bare call.
+	if got := c.Active(now); len(got) != 2 || got[0].Key != "N0CALL" || got[1].Key != "N0CALL-9" {
+		t.Fatalf("Active = %+v, want N0CALL and N0CALL-9", got)
+	}
+
+	// 35 minutes in, the mobile has expired but home has not.
+	now = start.Add(35 * time.Minute)
+	if got := c.Active(now); len(got) != 1 || got[0].Key != "N0CALL" {
+		t.Errorf("Active after expiry = %+v, want only N0CALL", got)
+	}
+	if _, ok := c.Get("N0CALL-9", now); ok {
+		t.Error("Get returned an expired entry")
+	}
+	if n := c.Prune(now); n != 1 || c.Len() != 1 {
+		t.Errorf("Prune removed %d (Len %d), want 1 (Len 1)", n, c.Len())
+	}
+}
+
+func TestKeyMatchesSameStation(t *testing.T) {
+	froms := []string{"N0CALL", "n0call-0", "N0CALL-9", "n0call-9", "N0CALL-AB", "N1CALL"}
+	for _, a := range froms {
+		for _, b := range froms {
+			pa := mustParse(t, a+">APRS:>x")
+			pb := mustParse(t, b+">APRS:>x")
+			if same := Key(&pa) == Key(&pb); same != aprsutils.SameStation(a, b, false) {
+				t.Errorf("Key(%s) == Key(%s) is %v, SameStation disagrees", a, b, same)
+			}
+		}
+	}
+}
+
+func TestPositionCacheObjects(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewPositionCache(time.Hour)
+
+	c.UpdateAt(mustParse(t, "SRC>APRS:;EVENT    *111111z4903.50N/07201.75W-Event"), now)
+	if _, ok := c.Get(ObjectPrefix+"EVENT", now); !ok {
+		t.Fatal("object not keyed by its name")
+	}
+
+	// A station called EVENT does not overwrite the object.
+	c.UpdateAt(mustParse(t, "EVENT>APRS:!4800.00N/07000.00W-station"), now)
+	if e, ok := c.Get(ObjectPrefix+"EVENT", now); !ok || e.Lat < 49 {
+		t.Errorf("object entry = %+v, %v after a same-named station", e, ok)
+	}
+	if _, ok := c.Get("EVENT", now); !ok {
+		t.Error("station EVENT not stored next to the object")
+	}
+
+	c.UpdateAt(mustParse(t, "SRC>APRS:;EVENT    _111111z4903.50N/07201.75W-Event"), now)
+	if _, ok := c.Get(ObjectPrefix+"EVENT", now); ok {
+		t.Error("killed object was not removed")
+	}
+	if _, ok := c.Get("EVENT", now); !ok {
+		t.Error("killing the object removed the station")
+	}
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/APRSCN/aprsutils"
+	"github.com/APRSCN/aprsutils/parser"
+)
+
+// TacticalAddressee is the pseudo-addressee of tactical callsign assignment
+// messages.
+const TacticalAddressee = "TACTICAL"
+
+// TacticalAssignment maps a station callsign to a tactical name.
+type TacticalAssignment struct {
+	Callsign   string // station callsign as aprsutils.CallsignKey
+	Tactical   string // tactical name; "" clears the assignment
+	AssignedBy string // source callsign of the assignment message
+}
+
+// ParseTactical extracts the tactical assignments carried by p. The
+// convention (APRSIS32, aprs.fi) is a message addressed to "TACTICAL" whose
+// text is a ';'-separated list of CALL=NAME pairs:
+//
+//	N0CALL>APRS::TACTICAL :N0CALL-9=MEDIC1;N1CALL=NET
+//
+// An empty name ("N0CALL-9=") clears the assignment. Pairs without '=' or
+// without a callsign are skipped. It returns nil for any other packet.
+func ParseTactical(p *parser.Parsed) []TacticalAssignment {
+	if p.Format != "message" || !strings.EqualFold(p.Addressee, TacticalAddressee) {
+		return nil
+	}
+
+	var assignments []TacticalAssignment
+	for _, pair := range strings.Split(p.MessageText, ";") {
+		call, name, ok := strings.Cut(pair, "=")
+		call = aprsutils.CallsignKey(call)
+		if !ok || call == "" {
+			continue
+		}
+		assignments = append(assignments, TacticalAssignment{
+			Callsign:   call,
+			Tactical:   strings.TrimSpace(name),
+			AssignedBy: p.From,
+		})
+	}
+	return assignments
+}
+
+// TacticalStore resolves station callsigns to the tactical names assigned to
+// them by TACTICAL messages. It is safe for concurrent use.
+type TacticalStore struct {
+	mu          sync.RWMutex
+	assignments map[string]TacticalAssignment
+}
+
+// NewTacticalStore creates an empty tactical store.
+func NewTacticalStore() *TacticalStore {
+	return &TacticalStore{assignments: make(map[string]TacticalAssignment)}
+}
+
+// Ingest applies the tactical assignments carried by p (see ParseTactical);
+// later assignments replace earlier ones. It reports whether p was a tactical
+// assignment message.
+func (s *TacticalStore) Ingest(p parser.Parsed) bool {
+	assignments := ParseTactical(&p)
+	if len(assignments) == 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range assignments {
+		if a.Tactical == "" {
+			delete(s.assignments, a.Callsign)
+			continue
+		}
+		s.assignments[a.Callsign] = a
+	}
+	return true
+}
+
+// Resolve returns the tactical name assigned to callsign, matched as
+// aprsutils.SameStation does: case-insensitive, with "-0" equal to no SSID.
+func (s *TacticalStore) Resolve(callsign string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.assignments[aprsutils.CallsignKey(callsign)]
+	return a.Tactical, ok
+}
+
+// Assignments returns every current assignment, sorted by callsign.
+func (s *TacticalStore) Assignments() []TacticalAssignment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]TacticalAssignment, 0, len(s.assignments))
+	for _, a := range s.assignments {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Callsign < out[j].Callsign })
+	return out
+}
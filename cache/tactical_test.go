@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTacticalStore(t *testing.T) {
+	s := NewTacticalStore()
+
+	if s.Ingest(mustParse(t, "N0CALL>APRS::N1CALL   :hello")) {
+		t.Error("ordinary message ingested as tactical assignment")
+	}
+
+	if !s.Ingest(mustParse(t, "NETCTL>APRS::TACTICAL :N0CALL-9=MEDIC1;n1call=NET;bogus")) {
+		t.Fatal("tactical assignment not ingested")
+	}
+	if name, ok := s.Resolve("n0call-9"); !ok || name != "MEDIC1" {
+		t.Errorf("Resolve(n0call-9) = %q, %v; want MEDIC1", name, ok)
+	}
+	want := []TacticalAssignment{
+		{Callsign: "N0CALL-9", Tactical: "MEDIC1", AssignedBy: "NETCTL"},
+		{Callsign: "N1CALL", Tactical: "NET", AssignedBy: "NETCTL"},
+	}
+	if got := s.Assignments(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Assignments() = %+v, want %+v", got, want)
+	}
+
+	// "-0" is no SSID, whichever side carries it.
+	s.Ingest(mustParse(t, "NETCTL>APRS::TACTICAL :N2CALL-0=BASE"))
+	if name, ok := s.Resolve("n2call"); !ok || name != "BASE" {
+		t.Errorf("Resolve(n2call) = %q, %v; want BASE", name, ok)
+	}
+	s.Ingest(mustParse(t, "NETCTL>APRS::TACTICAL :N2CALL=CAMP"))
+	if name, ok := s.Resolve("N2CALL-0"); !ok || name != "CAMP" || len(s.Assignments()) != 3 {
+		t.Errorf("Resolve(N2CALL-0) = %q, %v with %d assignments; want CAMP of 3", name, ok, len(s.Assignments()))
+	}
+
+	// An empty name clears the assignment.
+	s.Ingest(mustParse(t, "NETCTL>APRS::TACTICAL :N1CALL="))
+	if _, ok := s.Resolve("N1CALL"); ok {
+		t.Error("N1CALL still resolves after clearing")
+	}
+}
@@ -1,13 +1,80 @@
 package aprsutils
 
 import (
+	"strings"
+
 	"github.com/APRSCN/aprsutils/utils"
-	"go.gh.ink/regexp"
 )
 
-// ValidateCallsign checks whether a callsign is valid
+// ValidateCallsign checks whether a callsign is valid on APRS-IS. It is
+// permissive: up to 9 characters in total, any case, with an optional
+// alphanumeric SSID of up to 8 characters (e.g. "N0CALL-AB"). This is what
+// the parser and the APRS-IS side of the library use.
 func ValidateCallsign(callsign string) bool {
 	// Match
 	return (1 <= utils.StringLen(callsign) && utils.StringLen(callsign) <= 9) &&
-		regexp.MustCompile(`(?i)^[a-z0-9]{0,9}(-[a-z0-9]{1,8})?$`).MatchString(callsign)
+		CompiledRegexps.MustCompile(`(?i)^[a-z0-9]{0,9}(-[a-z0-9]{1,8})?$`).MatchString(callsign)
+}
+
+// ValidateAX25Callsign checks whether a callsign can be carried in an AX.25
+// address field on RF: a base of 1-6 upper-case letters or digits and an
+// optional numeric SSID 0-15 without leading zeros. Use it wherever a
+// callsign is encoded into a frame (e.g. KISS); ValidateCallsign accepts
+// APRS-IS-only forms such as "N0CALL-AB" or "LONGCALL7" that this rejects.
+func ValidateAX25Callsign(callsign string) bool {
+	return CompiledRegexps.MustCompile(`^[A-Z0-9]{1,6}(-(1[0-5]|[0-9]))?$`).MatchString(callsign)
+}
+
+// SplitCallsign splits a callsign into its base call and SSID. Surrounding
+// spaces and a trailing "used" marker ('*') are removed; the SSID is "" when
+// absent.
+func SplitCallsign(callsign string) (string, string) {
+	callsign = strings.TrimSuffix(strings.TrimSpace(callsign), "*")
+	base, ssid, ok := utils.SplitOnce(callsign, "-")
+	if !ok {
+		return callsign, ""
+	}
+	return base, ssid
+}
+
+// StationKey returns the canonical grouping key of a callsign: the upper-cased
+// base call with any SSID removed, so "n0call-9" and "N0CALL" share a key.
+func StationKey(callsign string) string {
+	base, _ := SplitCallsign(callsign)
+	return strings.ToUpper(base)
+}
+
+// SameStation reports whether callsigns a and b identify the same station.
+// Comparison is case-insensitive and ignores surrounding spaces and a trailing
+// '*'; a missing SSID equals "-0". With ignoreSSID only the base calls are
+// compared, so "N0CALL-1" and "n0call-7" match (one operator); otherwise
+// SSIDs, including alphanumeric ones like "-AB", must match too.
+func SameStation(a, b string, ignoreSSID bool) bool {
+	baseA, ssidA := SplitCallsign(a)
+	baseB, ssidB := SplitCallsign(b)
+	if baseA == "" || !strings.EqualFold(baseA, baseB) {
+		return false
+	}
+	if ignoreSSID {
+		return true
+	}
+	if ssidA == "0" {
+		ssidA = ""
+	}
+	if ssidB == "0" {
+		ssidB = ""
+	}
+	return strings.EqualFold(ssidA, ssidB)
+}
+
+// CallsignKey returns the canonical key of a callsign, SSID included: upper
+// case, without surrounding spaces, a trailing '*' or a "-0" SSID. Two
+// non-empty callsigns share a key exactly when SameStation(a, b, false)
+// reports them equal; use it to index stations by exact callsign.
+func CallsignKey(callsign string) string {
+	base, ssid := SplitCallsign(callsign)
+	if ssid == "" || ssid == "0" {
+		return strings.ToUpper(base)
+	}
+	return strings.ToUpper(base + "-" + ssid)
 }
@@ -0,0 +1,86 @@
+package aprsutils
+
+import "testing"
+
+func TestSplitCallsign(t *testing.T) {
+	cases := []struct {
+		in, base, ssid string
+	}{
+		{"N0CALL", "N0CALL", ""},
+		{"N0CALL-9", "N0CALL", "9"},
+		{"n0call-15", "n0call", "15"},
+		{"WIDE2-1*", "WIDE2", "1"},
+		{" N0CALL-AB ", "N0CALL", "AB"},
+	}
+	for _, c := range cases {
+		base, ssid := SplitCallsign(c.in)
+		if base != c.base || ssid != c.ssid {
+			t.Errorf("SplitCallsign(%q) = %q, %q; want %q, %q", c.in, base, ssid, c.base, c.ssid)
+		}
+	}
+}
+
+func TestStationKey(t *testing.T) {
+	for _, call := range []string{"N0CALL", "N0CALL-0", "n0call-9", "N0Call-15"} {
+		if got := StationKey(call); got != "N0CALL" {
+			t.Errorf("StationKey(%q) = %q, want N0CALL", call, got)
+		}
+	}
+}
+
+func TestValidateAX25Callsign(t *testing.T) {
+	cases := []struct {
+		call       string
+		ax25, aprs bool
+	}{
+		{"N0CALL", true, true},
+		{"N0CALL-0", true, true},
+		{"N0CALL-15", true, true},
+		{"N0CALL-16", false, true},
+		{"N0CALL-05", false, true},
+		{"N0CALL-AB", false, true},
+		{"ABCDEF-9", true, true},
+		{"ABCDEFG", false, true},   // 7-char base
+		{"ABCDEFGHI", false, true}, // 9-char base
+		{"n0call", false, true},    // AX.25 is upper-case only
+		{"N0CALL-", false, false},
+		{"", false, false},
+	}
+	for _, c := range cases {
+		if got := ValidateAX25Callsign(c.call); got != c.ax25 {
+			t.Errorf("ValidateAX25Callsign(%q) = %v, want %v", c.call, got, c.ax25)
+		}
+		if got := ValidateCallsign(c.call); got != c.aprs {
+			t.Errorf("ValidateCallsign(%q) = %v, want %v", c.call, got, c.aprs)
+		}
+	}
+}
+
+func TestSameStation(t *testing.T) {
+	cases := []struct {
+		a, b           string
+		exact, anySSID bool
+	}{
+		{"N0CALL", "N0CALL", true, true},
+		{"N0CALL", "n0call", true, true},
+		{"N0CALL", "N0CALL-0", true, true},
+		{"N0CALL-1", "N0CALL-7", false, true},
+		{"N0CALL-9", "n0call-9*", true, true},
+		{"N0CALL-AB", "n0call-ab", true, true},
+		{"N0CALL-AB", "N0CALL", false, true},
+		{"N0CALL", "N0CALLS", false, false},
+		{"N0CALL-1", "N1CALL-1", false, false},
+		{"", "", false, false},
+	}
+	for _, c := range cases {
+		if got := SameStation(c.a, c.b, false); got != c.exact {
+			t.Errorf("SameStation(%q, %q, false) = %v, want %v", c.a, c.b, got, c.exact)
+		}
+		if got := SameStation(c.a, c.b, true); got != c.anySSID {
+			t.Errorf("SameStation(%q, %q, true) = %v, want %v", c.a, c.b, got, c.anySSID)
+		}
+		if same := CallsignKey(c.a) == CallsignKey(c.b); c.a != "" && same != c.exact {
+			t.Errorf("CallsignKey(%q) == CallsignKey(%q) is %v, want %v", c.a, c.b, same, c.exact)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Default reconnect backoff: 1s before the first attempt, then 3s between
+// attempts.
+const (
+	defaultBackoffInitial = 1 * time.Second
+	defaultBackoffMax     = 3 * time.Second
+	defaultBackoffFactor  = 3
+)
+
+// backoff holds the reconnect delay parameters set by WithReconnectBackoff.
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+	factor  float64
+}
+
+// WithReconnectBackoff sets the delay before each reconnection attempt after
+// the link drops: initial before the first, multiplied by factor for every
+// further attempt, and capped at max. Each delay is jittered down by up to
+// half, so clients dropped together do not reconnect in lockstep. Values
+// that are not positive (or a factor below 1) keep the default of 1s, 3s, 3.
+func WithReconnectBackoff(initial, max time.Duration, factor float64) Option {
+	return func(c *Client) {
+		if initial > 0 {
+			c.backoff.initial = initial
+		}
+		if max > 0 {
+			c.backoff.max = max
+		}
+		if factor >= 1 {
+			c.backoff.factor = factor
+		}
+	}
+}
+
+// delay returns the jittered wait before reconnection attempt n (from 0). It
+// lies in [d/2, d] for the nominal delay d, so with a factor of at least 2 it
+// does not shrink from one attempt to the next until max is reached.
+func (b backoff) delay(n int) time.Duration {
+	d := float64(b.initial) * math.Pow(b.factor, float64(n))
+	if d > float64(b.max) || math.IsInf(d, 0) {
+		d = float64(b.max)
+	}
+	return time.Duration(d/2 + rand.Float64()*d/2)
+}
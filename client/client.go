@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	"github.com/APRSCN/aprsutils"
+	"github.com/APRSCN/aprsutils/parser"
+	"github.com/APRSCN/aprsutils/qConstruct"
 	"go.gh.ink/toolbox/xfmt"
 )
 
@@ -31,6 +34,10 @@ const (
 	UDP Protocol = "udp"
 )
 
+// ErrServerTimeout is the cause of a connection dropped because the server
+// sent nothing for longer than WithServerTimeout allows.
+var ErrServerTimeout = errors.New("server timed out")
+
 // Stats contains statistics for the client
 type Stats struct {
 	TotalSentBytes  uint64
@@ -41,6 +48,15 @@ type Stats struct {
 	PacketsReceived uint64
 	ConnectionTime  time.Duration
 	LastActivity    time.Time
+	EventsDropped   uint64 // events lost to a full WithEventChannel channel
+}
+
+// ReceivedPacket is a packet delivered by WithReceivedHandler.
+type ReceivedPacket struct {
+	Raw        string
+	Parsed     *parser.Parsed // as far as it decoded; see ParseErr
+	ParseErr   error          // error from parser.Parse, if any
+	ReceivedAt time.Time      // when the client read the packet
 }
 
 // Client provides a basic struct of Client object
@@ -57,18 +73,69 @@ type Client struct {
 	retryTimes int
 	logger     aprsutils.Logger
 	handler    func(packet string)
+	onReceived func(ReceivedPacket)
+	onParsed   func(parser.Parsed, error)
+	life       context.Context         // bounds reconnection; done on Close
+	endLife    context.CancelFunc      // cancels life
+	onPanic    func(r any, raw string) // called when handler panics
+	onLogin    func(verified bool, serverID string)
+	events     chan<- Event
+	heard      *heardList
+	formats    *formatCounts
+	raw        *rawRing
+	onState    func(old, new ConnectionState)
+	packets    packetFeed
 	server     string // server software banner
 	serverID   string // server callsign from logresp
+	loggedIn   bool   // a logresp line has been received
+	verified   bool   // logresp reported the login as verified
 	software   string
 	version    string
 
+	autoPasscode bool
+
+	// rejected is the last rejection announced by the server (see
+	// Rejection); rejectStreak counts consecutive connections refused with
+	// ErrServerFull, each of which uses up a reconnection attempt.
+	rejected     error
+	rejectStreak int
+
+	// lastErr is the last connection failure (see LastError).
+	lastErr error
+
+	// backoff spaces out reconnection attempts (see WithReconnectBackoff).
+	backoff backoff
+
+	// state is the connection state (see State), guarded by stateMu rather
+	// than mu so it can be changed once mu is released.
+	stateMu sync.Mutex
+	state   ConnectionState
+
+	// sendOnly / clientOnly mark the server port as a send-only or
+	// client-only port (see WithSendOnlyPort, WithClientOnlyPort).
+	sendOnly   bool
+	clientOnly bool
+
+	// txToCall / txPath form the header SendInfo puts in front of an
+	// information field ("callsign>txToCall,txPath...:info").
+	txToCall string
+	txPath   []string
+
 	conn    net.Conn
 	bufSize int
 
+	// clock is the time source for timers, sleeps and timestamps (see
+	// clock.go); it defaults to the wall clock.
+	clock clock
+
 	// readTimeout is the per-read deadline applied while receiving from the
 	// server (0 means the built-in default of 30s).
 	readTimeout time.Duration
 
+	// serverTimeout drops a connection the server has been silent on for
+	// that long (0 disables it, see WithServerTimeout).
+	serverTimeout time.Duration
+
 	// TCP keepalive parameters for the connection. When kaEnable is true they
 	// are applied to the connected TCP socket so a dead idle peer is detected.
 	kaEnable   bool
@@ -111,6 +178,8 @@ type Client struct {
 	currentSentRate atomic.Uint64 // last computed send rate (bytes/s)
 	currentRecvRate atomic.Uint64 // last computed recv rate (bytes/s)
 	lastActivity    atomic.Int64  // unix nanoseconds of last send/recv (0 = none)
+	eventsDropped   atomic.Uint64 // events dropped because the channel was full
+	parseErrors     atomic.Uint64 // received packets that failed to parse
 
 	// statsMu guards lastStatsUpdate, which is normally touched only by the
 	// single updateStats goroutine but may also be reset by ResetStats.
@@ -120,11 +189,22 @@ type Client struct {
 
 // Export data
 
+// LastError returns the last connection failure: a failed dial or login, or
+// the error that dropped an established link. It is nil until something
+// fails and is cleared by every successful connect, including a reconnect.
+func (c *Client) LastError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastErr
+}
+
 func (c *Client) Callsign() string {
 	return c.callsign
 }
 
 func (c *Client) Filter() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.filter
 }
 
@@ -170,6 +250,53 @@ func (c *Client) ServerID() string {
 	return c.serverID
 }
 
+// Verified reports whether the server accepted the login as verified. Before
+// the logresp line arrives it reports whether the configured passcode is valid
+// for the callsign.
+func (c *Client) Verified() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.verifiedLocked()
+}
+
+// verifiedLocked is Verified without locking; c.mu must be held.
+func (c *Client) verifiedLocked() bool {
+	if c.loggedIn {
+		return c.verified
+	}
+	return c.passcode == aprsutils.PasscodeString(c.callsign)
+}
+
+// ConnectionType returns the q-construct connection type this client's
+// uplink corresponds to, for use as qConstruct.QConfig.ConnectionType:
+//
+//	UDP (submit)                -> ConnectionDirectUDP
+//	TCP, unverified login       -> ConnectionUnverified
+//	TCP, verified, Fullfeed     -> ConnectionVerified
+//	TCP, verified, IGate        -> ConnectionVerified
+//	TCP, verified, send-only    -> ConnectionSendOnly (WithSendOnlyPort)
+//	TCP, verified, client-only  -> ConnectionClientOnly (WithClientOnlyPort)
+//
+// Verification is taken from the server's logresp once received, otherwise
+// assumed from the passcode (see Verified).
+func (c *Client) ConnectionType() qConstruct.ConnectionType {
+	if c.protocol == UDP {
+		return qConstruct.ConnectionDirectUDP
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case !c.verifiedLocked():
+		return qConstruct.ConnectionUnverified
+	case c.sendOnly:
+		return qConstruct.ConnectionSendOnly
+	case c.clientOnly:
+		return qConstruct.ConnectionClientOnly
+	}
+	return qConstruct.ConnectionVerified
+}
+
 // RemoteAddr returns the resolved remote address of the active connection
 // (e.g. "44.135.0.1:10152"), or "" if not connected. Unlike Host(), which is
 // the configured (possibly DNS) hostname, this reflects the actual IP a
@@ -196,6 +323,7 @@ func (c *Client) GetStats() Stats {
 		CurrentRecvRate: c.currentRecvRate.Load(),
 		PacketsSent:     c.packetsSent.Load(),
 		PacketsReceived: c.packetsReceived.Load(),
+		EventsDropped:   c.eventsDropped.Load(),
 	}
 
 	// Connection time (only meaningful while up).
@@ -203,7 +331,7 @@ func (c *Client) GetStats() Stats {
 	up, uptime := c.up, c.uptime
 	c.mu.Unlock()
 	if up && !uptime.IsZero() {
-		s.ConnectionTime = time.Since(uptime)
+		s.ConnectionTime = c.clock.Now().Sub(uptime)
 	}
 
 	if na := c.lastActivity.Load(); na != 0 {
@@ -223,9 +351,10 @@ func (c *Client) ResetStats() {
 	c.currentRecv.Store(0)
 	c.currentSentRate.Store(0)
 	c.currentRecvRate.Store(0)
+	c.eventsDropped.Store(0)
 
 	c.statsMu.Lock()
-	c.lastStatsUpdate = time.Now()
+	c.lastStatsUpdate = c.clock.Now()
 	c.statsMu.Unlock()
 }
 
@@ -246,6 +375,56 @@ func WithHandler(handler func(packet string)) Option {
 	}
 }
 
+// WithReceivedHandler sets a callback receiving every packet parsed and
+// stamped with its reception time, for packets that carry no timestamp of
+// their own. It is called before the WithHandler handler, and its panics are
+// recovered the same way.
+func WithReceivedHandler(onReceived func(ReceivedPacket)) Option {
+	return func(c *Client) {
+		c.onReceived = onReceived
+	}
+}
+
+// WithParsedHandler sets a callback receiving every packet as decoded by
+// parser.Parse, with the parse error, so consumers need not parse the raw
+// line themselves. It is called after the WithReceivedHandler callback and
+// before the WithHandler handler; unless WithHandler is also given, the
+// default handler, which only logs each packet, is not used.
+func WithParsedHandler(onParsed func(parser.Parsed, error)) Option {
+	return func(c *Client) {
+		c.onParsed = onParsed
+	}
+}
+
+// WithHandlerPanicHandler sets the callback receiving a panic recovered while
+// handling a received packet, in the packet handler or in decoding it (r is
+// the recovered value, raw the packet being handled). The client keeps running either way; without a callback the
+// panic is logged.
+func WithHandlerPanicHandler(onPanic func(r any, raw string)) Option {
+	return func(c *Client) {
+		c.onPanic = onPanic
+	}
+}
+
+// WithLoginHandler sets a callback fired for every "# logresp" line with the
+// verification state and server callsign it reports. Servers may send a
+// later logresp, e.g. turning an unverified login verified mid-session, so
+// it can fire more than once per connection.
+func WithLoginHandler(onLogin func(verified bool, serverID string)) Option {
+	return func(c *Client) {
+		c.onLogin = onLogin
+	}
+}
+
+// WithEventChannel delivers structured connection events (connect, login,
+// disconnect, reconnect) to ch. Sends never block the client: when ch is
+// full the event is dropped and counted in Stats.EventsDropped.
+func WithEventChannel(ch chan<- Event) Option {
+	return func(c *Client) {
+		c.events = ch
+	}
+}
+
 // WithSoftwareAndVersion sets default software name and version to custom
 func WithSoftwareAndVersion(software string, version string) Option {
 	return func(c *Client) {
@@ -272,6 +451,43 @@ func WithRetryTimes(retryTimes int) Option {
 	}
 }
 
+// WithSendOnlyPort marks the server port as a send-only port, one that
+// accepts packets but sends no feed back, so ConnectionType reports
+// qConstruct.ConnectionSendOnly for a verified login.
+func WithSendOnlyPort() Option {
+	return func(c *Client) {
+		c.sendOnly = true
+	}
+}
+
+// WithClientOnlyPort marks the server port as a client-only port, one that
+// serves clients but does not peer with other servers, so ConnectionType
+// reports qConstruct.ConnectionClientOnly for a verified login.
+func WithClientOnlyPort() Option {
+	return func(c *Client) {
+		c.clientOnly = true
+	}
+}
+
+// WithTXPath sets the destination (tocall) and digipeater path SendInfo uses
+// when building packets. The defaults are "APRS" and "TCPIP*".
+func WithTXPath(tocall string, path []string) Option {
+	return func(c *Client) {
+		c.txToCall = tocall
+		c.txPath = append([]string(nil), path...)
+	}
+}
+
+// WithAutoPasscode computes the APRS-IS passcode from the callsign when
+// NewClient is given an empty passcode, so a verified login needs only the
+// callsign. An explicit passcode is kept, and N0CALL (which cannot be
+// verified) still logs in without one.
+func WithAutoPasscode() Option {
+	return func(c *Client) {
+		c.autoPasscode = true
+	}
+}
+
 // WithBufSize sets a custom buf size for reader
 func WithBufSize(bufSize int) Option {
 	return func(c *Client) {
@@ -289,6 +505,19 @@ func WithReadTimeout(d time.Duration) Option {
 	}
 }
 
+// WithServerTimeout makes the client drop a connection on which the server has
+// sent nothing at all for d, and reconnect. APRS-IS servers send a "#" comment
+// line about every 20 seconds even when no packets flow, so a silent link is a
+// dead (e.g. half-open) one; 60s is a reasonable value. A zero or negative d
+// disables the check, which is the default.
+func WithServerTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.serverTimeout = d
+		}
+	}
+}
+
 // WithKeepAlive enables TCP keepalive on the connection: probing starts after
 // the socket has been idle for idle, probes are sent every interval, and the
 // link is dropped after count failed probes. It has no effect on UDP.
@@ -311,7 +540,8 @@ func WithLocalAddr(v4, v6 string) Option {
 	}
 }
 
-// NewClient creates a new APRS client
+// NewClient creates a new APRS client. A zero port selects the standard
+// port for the mode (DefaultPort), or PortUDPSubmit for UDP.
 func NewClient(
 	callsign string, passcode string,
 	mode Mode, protocol Protocol,
@@ -320,40 +550,76 @@ func NewClient(
 ) *Client {
 	// Create client
 	c := &Client{
-		callsign:        callsign,
-		passcode:        passcode,
-		mode:            mode,
-		protocol:        protocol,
-		host:            host,
-		port:            port,
-		software:        aprsutils.Name,
-		version:         aprsutils.Version,
-		done:            make(chan struct{}),
-		lastStatsUpdate: time.Now(),
+		callsign: callsign,
+		passcode: passcode,
+		mode:     mode,
+		protocol: protocol,
+		host:     host,
+		port:     port,
+		software: aprsutils.Name,
+		version:  aprsutils.Version,
+		done:     make(chan struct{}),
+		clock:    realClock{},
+		state:    Disconnected,
+		backoff: backoff{
+			initial: defaultBackoffInitial,
+			max:     defaultBackoffMax,
+			factor:  defaultBackoffFactor,
+		},
 	}
 
+	c.life, c.endLife = context.WithCancel(context.Background())
+
 	// Check callsign
 	if callsign == "" {
 		c.callsign = "N0CALL"
 	}
 
+	// Default port
+	if port == 0 {
+		c.port = DefaultPort(mode, false)
+		if protocol == UDP {
+			c.port = PortUDPSubmit
+		}
+	}
+
 	// Load default logger
 	c.logger = aprsutils.NewLogger()
 
-	// Set default handler
-	c.handler = c.handlePacket
-
 	// Set default retry times
 	c.retryTimes = 5
 
 	// Set default buf size
 	c.bufSize = 1024
 
+	// Set default TX header
+	c.txToCall = aprsutils.DefaultToCall
+	c.txPath = []string{"TCPIP*"}
+
 	// Apply options
 	for _, option := range options {
 		option(c)
 	}
 
+	// Set default handler
+	if c.handler == nil {
+		c.handler = c.handlePacket
+		if c.onParsed != nil {
+			c.handler = func(string) {}
+		}
+	}
+
+	// Compute passcode
+	if c.autoPasscode && c.passcode == "" {
+		base, _, _ := strings.Cut(c.callsign, "-")
+		if !strings.EqualFold(base, "N0CALL") {
+			c.passcode = aprsutils.PasscodeString(c.callsign)
+		}
+	}
+
+	// Start the first stats window now that the clock is settled
+	c.lastStatsUpdate = c.clock.Now()
+
 	return c
 }
 
@@ -365,6 +631,33 @@ func NewClient(
 // receive loop; instead every SendPacket datagram is prefixed with the login
 // line (see SendPacket).
 func (c *Client) Connect() error {
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext is Connect with a context: the dial is aborted, with an
+// error wrapping ctx.Err(), when ctx is done first. ctx bounds only this
+// dial; reconnection after the link drops goes on until Close, so cancelling
+// ctx once ConnectContext has returned has no effect.
+func (c *Client) ConnectContext(ctx context.Context) error {
+	return c.connect(ctx, false)
+}
+
+// connect dials and logs in. reconnect is set by the reconnect loop, which
+// stays Reconnecting across attempts instead of passing through Connecting
+// and Disconnected on each one.
+func (c *Client) connect(ctx context.Context, reconnect bool) (err error) {
+	if !reconnect {
+		c.transition(Connecting)
+	}
+	// Deferred before locking, so it runs once mu is released.
+	defer func() {
+		if err == nil {
+			c.transition(Connected)
+		} else if !reconnect {
+			c.transition(Disconnected)
+		}
+	}()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -374,23 +667,30 @@ func (c *Client) Connect() error {
 	}
 
 	// Build address
-	address := net.JoinHostPort(c.host, strconv.Itoa(c.port))
+	address := c.address()
 
 	network := "tcp"
 	if c.protocol == UDP {
 		network = "udp"
 	}
 
-	conn, err := c.dial(network, address)
+	conn, err := c.dial(ctx, network, address)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+			err = fmt.Errorf("%w: %w", ctxErr, err)
+		}
+		c.lastErr = fmt.Errorf("connect to %s: %w", address, err)
+		c.emit(Event{Type: EventConnectError, Error: err.Error()})
 		return err
 	}
+	c.lastErr = nil
 	c.up = true
-	c.uptime = time.Now()
-	c.lastActivity.Store(time.Now().UnixNano())
+	c.uptime = c.clock.Now()
+	c.lastActivity.Store(c.uptime.UnixNano())
 
 	c.conn = conn
 	c.logger.Info(context.TODO(), "Connected to ", address, " (", string(c.protocol), ")")
+	c.emit(Event{Type: EventConnect})
 
 	if c.protocol == UDP {
 		// Start the lifecycle stats updater once (UDP has no heartbeat).
@@ -427,30 +727,35 @@ func (c *Client) Connect() error {
 	return c.login()
 }
 
+// address returns the server address as host:port.
+func (c *Client) address() string {
+	return net.JoinHostPort(c.host, strconv.Itoa(c.port))
+}
+
 // dial opens a connection to address, optionally binding a configured local
 // source address chosen by the resolved remote address family.
-func (c *Client) dial(network, address string) (net.Conn, error) {
+func (c *Client) dial(ctx context.Context, network, address string) (net.Conn, error) {
 	dialer := net.Dialer{}
 
 	if c.localAddrV4 != "" || c.localAddrV6 != "" {
-		if la := c.localAddrFor(network, address); la != nil {
+		if la := c.localAddrFor(ctx, network, address); la != nil {
 			dialer.LocalAddr = la
 		}
 	}
 
-	return dialer.Dial(network, address)
+	return dialer.DialContext(ctx, network, address)
 }
 
 // localAddrFor resolves the remote address to determine its family and returns
 // the matching configured local address (or nil if none configured for that
 // family, or resolution fails — in which case the OS picks the source).
-func (c *Client) localAddrFor(network, address string) net.Addr {
+func (c *Client) localAddrFor(ctx context.Context, network, address string) net.Addr {
 	host, _, err := net.SplitHostPort(address)
 	if err != nil {
 		return nil
 	}
 
-	ips, err := net.LookupIP(host)
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
 	if err != nil || len(ips) == 0 {
 		return nil
 	}
@@ -479,35 +784,29 @@ func (c *Client) localAddrFor(network, address string) net.Addr {
 
 // precomputeUDPLogin builds the login line prepended to each UDP datagram.
 func (c *Client) precomputeUDPLogin() {
-	passcodeString := ""
-	if c.passcode != "" {
-		passcodeString = xfmt.Sprintf(" pass %s", c.passcode)
-	}
-	login := xfmt.Sprintf("user %s%s vers %s %s", c.callsign, passcodeString, c.software, c.version)
-	if c.mode != Fullfeed && c.filter != "" {
-		login += xfmt.Sprintf(" filter %s", c.filter)
+	c.udpLogin = c.loginLine() + "\r\n"
+}
+
+// loginLine returns the login line (without CRLF) for the client's settings.
+// Fullfeed ports take no filter, so it is only sent in the other modes.
+func (c *Client) loginLine() string {
+	filter := ""
+	if c.mode != Fullfeed {
+		filter = c.filter
 	}
-	c.udpLogin = strings.Join([]string{login, "\r\n"}, "")
+	return aprsutils.BuildLoginString(c.callsign, c.passcode, c.software, c.version, filter)
 }
 
 // Login to an APRS server
 func (c *Client) login() error {
 	// Construct login string
-	passcodeString := ""
-	if c.passcode != "" {
-		passcodeString = xfmt.Sprintf(" pass %s", c.passcode)
-	}
-	loginStr := xfmt.Sprintf("user %s%s vers %s %s", c.callsign, passcodeString, c.software, c.version)
-	// Maybe have a filter?
-	if c.mode != Fullfeed && c.filter != "" {
-		loginStr += xfmt.Sprintf(" filter %s", c.filter)
-	}
-	loginStr = strings.Join([]string{loginStr, "\r\n"}, "")
+	loginStr := c.loginLine() + "\r\n"
 
 	// Send login request
 	sent, err := c.conn.Write([]byte(loginStr))
 	if err != nil {
 		c.logger.Error(context.TODO(), "Error writing login command to ", c.conn.RemoteAddr().String(), err)
+		c.lastErr = fmt.Errorf("login to %s: %w", c.address(), err)
 		return err
 	}
 
@@ -515,13 +814,13 @@ func (c *Client) login() error {
 	c.addSentBytes(sent)
 
 	// Check passcode
-	if strconv.Itoa(aprsutils.Passcode(c.callsign)) == c.passcode {
+	if aprsutils.PasscodeString(c.callsign) == c.passcode {
 		c.logger.Info(context.TODO(), "Logged in as ", c.callsign)
 	}
 
 	// Start packet receiving for this connection. The stats updater and
 	// heartbeat are lifecycle-scoped and started once by Connect.
-	go c.receivePackets()
+	go c.receivePackets(c.conn)
 
 	return nil
 }
@@ -533,7 +832,7 @@ func (c *Client) addSentBytes(bytes int) {
 	}
 	c.totalSentBytes.Add(uint64(bytes))
 	c.currentSent.Add(uint64(bytes))
-	c.lastActivity.Store(time.Now().UnixNano())
+	c.lastActivity.Store(c.clock.Now().UnixNano())
 }
 
 // addRecvBytes records bytes read from the server (direct atomic update).
@@ -543,20 +842,20 @@ func (c *Client) addRecvBytes(bytes int) {
 	}
 	c.totalRecvBytes.Add(uint64(bytes))
 	c.currentRecv.Add(uint64(bytes))
-	c.lastActivity.Store(time.Now().UnixNano())
+	c.lastActivity.Store(c.clock.Now().UnixNano())
 }
 
 // updateStats periodically updates the current rate statistics
 func (c *Client) updateStats() {
-	ticker := time.NewTicker(1 * time.Second)
+	ticker := c.clock.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-c.done:
 			return
-		case <-ticker.C:
-			now := time.Now()
+		case <-ticker.C():
+			now := c.clock.Now()
 
 			c.statsMu.Lock()
 			elapsed := now.Sub(c.lastStatsUpdate).Seconds()
@@ -575,9 +874,52 @@ func (c *Client) updateStats() {
 	}
 }
 
-// internalHandler handles packet first to do statistic
+// internalHandler handles packet first to do statistic. A panic while
+// handling it, in the parser or a handler, is recovered so it cannot take
+// down the receive loop; the panic goes to the WithHandlerPanicHandler
+// callback, or is logged.
 func (c *Client) internalHandler(packet string) {
+	// Recover first: parsing a line off the wire can panic as well as the
+	// user callbacks, and neither may take the receive loop down.
+	defer func() {
+		if r := recover(); r != nil {
+			if c.onPanic != nil {
+				c.onPanic(r, packet)
+				return
+			}
+			c.logger.Error(context.TODO(), "Packet handler panicked: ", r, " packet: ", packet)
+		}
+	}()
+
+	now := c.clock.Now()
+	if c.raw != nil {
+		c.raw.add(packet)
+	}
+	var parsed *parser.Parsed
+	var parseErr error
+	if c.heard != nil || c.formats != nil || c.onReceived != nil || c.onParsed != nil || c.events != nil {
+		p, err := parser.Parse(packet)
+		parsed, parseErr = &p, err
+	}
+	if parseErr != nil {
+		n := c.parseErrors.Add(1)
+		c.emit(Event{Type: EventParseError, Packet: packet, Count: n, Error: parseErr.Error()})
+	}
+	if c.heard != nil {
+		c.heard.add(parsed, now)
+	}
+	if c.formats != nil {
+		c.formats.add(parsed, parseErr)
+	}
 	c.packetsReceived.Add(1)
+	c.deliver(packet)
+
+	if c.onReceived != nil {
+		c.onReceived(ReceivedPacket{Raw: packet, Parsed: parsed, ParseErr: parseErr, ReceivedAt: now})
+	}
+	if c.onParsed != nil {
+		c.onParsed(*parsed, parseErr)
+	}
 	c.handler(packet)
 }
 
@@ -586,46 +928,68 @@ func (c *Client) internalHandler(packet string) {
 // (or retryTimes is 0, i.e. reconnection is owned by an external supervisor)
 // it signals the client done so a blocked Wait() returns. A successful
 // reconnect hands the lifecycle to the fresh receive loop, so this one returns
-// without signalling done.
-func (c *Client) receivePackets() {
+// without signalling done. conn is the connection it reads, passed in because
+// Close may clear c.conn at any time.
+func (c *Client) receivePackets(conn net.Conn) {
 	// reconnected is set when a successful Connect() has handed the lifecycle
 	// to a new receive loop; in that case we must not signal done. On every
 	// other return path the link is permanently down, so we release Wait().
 	reconnected := false
 	defer func() {
 		if !reconnected {
+			c.transition(Disconnected)
 			c.signalDone()
 		}
 	}()
 
 	// Create a reader
-	reader := bufio.NewReaderSize(c.conn, c.bufSize)
+	reader := bufio.NewReaderSize(conn, c.bufSize)
 
 	readTimeout := c.readTimeout
 	if readTimeout <= 0 {
 		readTimeout = 30 * time.Second
 	}
+	lastRead := time.Now()
 
 	serverInfoCount := 0
+	var readErr error
 root:
 	for {
 		select {
 		case <-c.done:
+			c.emit(Event{Type: EventDisconnect})
 			return
 		default:
-			// Set timeout
-			if err := c.conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			// Set timeout, waking up in time to notice a silent server.
+			deadline := time.Now().Add(readTimeout)
+			if c.serverTimeout > 0 {
+				if silent := lastRead.Add(c.serverTimeout); silent.Before(deadline) {
+					deadline = silent
+				}
+			}
+			if err := conn.SetReadDeadline(deadline); err != nil {
 				c.logger.Error(context.TODO(), "Error setting read deadline (timeout) ", err)
+				readErr = err
 				break root
 			}
 
 			// Read string from reader
 			line, err := reader.ReadString('\n')
+			if line != "" {
+				lastRead = time.Now()
+			}
 			if err != nil {
 				if netErr, ok := errors.AsType[net.Error](err); ok && netErr.Timeout() {
+					if c.serverTimeout > 0 && time.Since(lastRead) >= c.serverTimeout {
+						c.logger.Warn(context.TODO(), "Server silent for ", c.serverTimeout, ", dropping the connection")
+						readErr = ErrServerTimeout
+						_ = conn.Close()
+						break root
+					}
 					// Timeout, retry
 					continue
 				}
+				readErr = err
 				if err.Error() == "EOF" {
 					c.logger.Warn(context.TODO(), "Server closed the connection")
 					break root
@@ -646,6 +1010,16 @@ root:
 			// Check prefix
 			if strings.HasPrefix(line, "#") {
 				c.logger.Debug(context.TODO(), "Server info: ", line)
+				if err := rejection(line); err != nil {
+					c.logger.Warn(context.TODO(), "Server rejected the connection: ", line)
+					c.mu.Lock()
+					c.rejected = err
+					c.mu.Unlock()
+					c.emit(Event{Type: EventRejected, Error: err.Error()})
+					_ = conn.Close()
+					readErr = err
+					break root
+				}
 				// server/serverID are read by the accessors from other
 				// goroutines, so publish them under the lock.
 				c.mu.Lock()
@@ -659,8 +1033,24 @@ root:
 						c.serverID = id
 					}
 				}
+				// "# logresp <call> verified|unverified, server <ID>"
+				logresp := false
+				if fields := strings.Fields(line); len(fields) >= 4 && fields[1] == "logresp" {
+					c.loggedIn = true
+					c.verified = strings.TrimSuffix(fields[3], ",") == "verified"
+					c.rejected = nil
+					c.rejectStreak = 0
+					logresp = true
+				}
+				verified, serverID := c.verified, c.serverID
 				c.mu.Unlock()
 				serverInfoCount++
+				if logresp {
+					c.emit(Event{Type: EventLogin, Verified: verified, ServerID: serverID})
+					if c.onLogin != nil {
+						c.onLogin(verified, serverID)
+					}
+				}
 				continue
 			}
 
@@ -672,7 +1062,19 @@ root:
 	// Update status
 	c.mu.Lock()
 	c.up = false
+	closed := c.closed
+	if readErr != nil && !closed {
+		c.lastErr = fmt.Errorf("receive from %s: %w", c.address(), readErr)
+	}
 	c.mu.Unlock()
+	c.transition(Disconnected)
+
+	// A read failing because Close shut the connection is not an error.
+	disconnect := Event{Type: EventDisconnect}
+	if readErr != nil && !closed {
+		disconnect.Error = readErr.Error()
+	}
+	c.emit(disconnect)
 
 	// Check closed
 	select {
@@ -681,21 +1083,32 @@ root:
 	default:
 	}
 
-	// Debounce
-	time.Sleep(1 * time.Second)
+	// A refused login would be refused again; give up. A full server is
+	// retried, but the attempts already spent on refused connections count.
+	first := 0
+	if errors.Is(readErr, ErrLoginRejected) {
+		c.logger.Error(context.TODO(), "Login rejected, not reconnecting")
+		return
+	}
+	if errors.Is(readErr, ErrServerFull) {
+		c.mu.Lock()
+		c.rejectStreak++
+		first = c.rejectStreak - 1
+		c.mu.Unlock()
+	}
 
 	// Reconnect
-	for i := 0; i < c.retryTimes; i++ {
-		// Check closed
-		select {
-		case <-c.done:
+	var lastErr error
+	for i := first; i < c.retryTimes; i++ {
+		c.transition(Reconnecting)
+		if !c.sleep(c.backoff.delay(i)) {
 			return
-		default:
 		}
 
-		if err := c.Connect(); err != nil {
+		c.emit(Event{Type: EventReconnect, Attempt: i + 1})
+		if err := c.connect(c.life, true); err != nil {
 			c.logger.Error(context.TODO(), "Error connecting to server", err, " retry ", i)
-			time.Sleep(3 * time.Second)
+			lastErr = err
 			continue
 		} else {
 			// A fresh receive loop now owns the client lifecycle; do not
@@ -704,6 +1117,27 @@ root:
 			return
 		}
 	}
+
+	// Out of attempts: the client is done.
+	if c.retryTimes > 0 {
+		giveUp := Event{Type: EventGaveUp}
+		if lastErr != nil {
+			giveUp.Error = lastErr.Error()
+		}
+		c.logger.Error(context.TODO(), "Giving up reconnecting after ", c.retryTimes, " attempts")
+		c.emit(giveUp)
+	}
+}
+
+// sleep waits d on the client clock, returning false early when the client
+// is closed.
+func (c *Client) sleep(d time.Duration) bool {
+	select {
+	case <-c.clock.After(d):
+		return true
+	case <-c.done:
+		return false
+	}
 }
 
 // handlePacket handles APRS packet that has received
@@ -764,19 +1198,89 @@ func (c *Client) SendPacket(packet string) error {
 	return nil
 }
 
+// SendRaw writes b to the connection exactly as given: no CRLF is appended
+// and, for UDP, no login line is prefixed. The caller is responsible for
+// framing, including the trailing newline. It holds the same lock as
+// SendPacket, so writes from the two never interleave.
+func (c *Client) SendRaw(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || c.closed {
+		return errors.New("client is closed or not connected")
+	}
+
+	sent, err := c.conn.Write(b)
+	if err != nil {
+		c.logger.Error(context.TODO(), "Error send raw bytes: ", err)
+		return err
+	}
+
+	// Update statistics
+	c.addSentBytes(sent)
+	c.packetsSent.Add(1)
+
+	return nil
+}
+
+// SetFilter replaces the server-side filter of a connected igate-mode TCP
+// client by sending a "#filter" command, without reconnecting. The new filter
+// is also used when the client logs in again after a reconnect.
+func (c *Client) SetFilter(filter string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || c.closed {
+		return errors.New("client is closed or not connected")
+	}
+	if c.mode == Fullfeed {
+		return errors.New("full feed clients do not use a filter")
+	}
+	if c.protocol != TCP {
+		return errors.New("filters need a TCP connection")
+	}
+	if strings.ContainsAny(filter, "\r\n") {
+		return errors.New("filter contains a line break")
+	}
+
+	sent, err := c.conn.Write([]byte("#filter " + filter + "\r\n"))
+	if err != nil {
+		c.logger.Error(context.TODO(), "Error send filter: ", err)
+		return err
+	}
+	c.addSentBytes(sent)
+	c.filter = filter
+
+	c.logger.Debug(context.TODO(), "Filter set: ", filter)
+	return nil
+}
+
+// SendInfo sends an information field (e.g. ">status" or a position report)
+// as a packet from the client's callsign, using the tocall and path set by
+// WithTXPath. Use SendPacket for full control over the header.
+func (c *Client) SendInfo(info string) error {
+	return c.SendPacket(c.buildPacket(info))
+}
+
+// buildPacket prefixes info with the client's TX header.
+func (c *Client) buildPacket(info string) string {
+	header := strings.Join(append([]string{c.callsign + ">" + c.txToCall}, c.txPath...), ",")
+	return strings.Join([]string{header, info}, ":")
+}
+
 // heartBeat sends a keepalive periodically for the whole client lifetime. It
 // is started once (see Connect) and survives reconnects: while the link is
 // down (conn == nil) it simply skips a tick; it only exits when the client is
 // closed.
 func (c *Client) heartBeat() {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := c.clock.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-c.done:
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			// Skip while disconnected; exit only when the client is closed.
 			c.mu.Lock()
 			if c.closed {
@@ -789,7 +1293,7 @@ func (c *Client) heartBeat() {
 			}
 			c.mu.Unlock()
 
-			ping := xfmt.Sprintf("# %s keepalive %d", c.software, time.Now().Unix())
+			ping := xfmt.Sprintf("# %s keepalive %d", c.software, c.clock.Now().Unix())
 			if err := c.SendPacket(ping); err != nil {
 				c.logger.Error(context.TODO(), "Heartbeat failed, connection may be closed")
 
@@ -813,6 +1317,9 @@ func (c *Client) Close() {
 		return
 	}
 
+	// Deferred before locking, so it runs once mu is released.
+	defer c.transition(Closed)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -830,6 +1337,10 @@ func (c *Client) Close() {
 			c.logger.Info(context.TODO(), "client closed")
 		}
 		c.conn = nil
+		// TCP reports the disconnect from its receive loop.
+		if c.protocol == UDP {
+			c.emit(Event{Type: EventDisconnect})
+		}
 	}
 }
 
@@ -848,10 +1359,25 @@ func (c *Client) Wait() {
 	<-c.done
 }
 
+// WaitContext is Wait bounded by ctx: it returns nil once the client is done,
+// or ctx.Err() if ctx is done first. The client itself is left running.
+func (c *Client) WaitContext(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // signalDone closes c.done exactly once. It marks the client as permanently
-// finished so a blocked Wait() returns. Unlike Close it does not tear down the
-// (already dead) connection; it is the path taken when receivePackets stops
-// reconnecting.
+// finished so a blocked Wait() returns and the Packets channel is closed.
+// Unlike Close it does not tear down the (already dead) connection; it is the
+// path taken when receivePackets stops reconnecting.
 func (c *Client) signalDone() {
-	c.doneOnce.Do(func() { close(c.done) })
+	c.doneOnce.Do(func() {
+		close(c.done)
+		c.endLife()
+		c.closePackets()
+	})
 }
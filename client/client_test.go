@@ -1,10 +1,22 @@
 package client
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"maps"
 	"net"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/APRSCN/aprsutils"
+	"github.com/APRSCN/aprsutils/parser"
+	"github.com/APRSCN/aprsutils/qConstruct"
 )
 
 // TestUDPSubmitDatagram verifies that a UDP-mode client prefixes the login
@@ -83,26 +95,10 @@ func TestProtocolAccessor(t *testing.T) {
 // to dial a fresh connection). A regression here would hang the uplink manager
 // forever after the first disconnect.
 func TestWaitReturnsAfterDropNoRetry(t *testing.T) {
-	ln, err := net.Listen("tcp", "127.0.0.1:0")
-	if err != nil {
-		t.Fatalf("listen: %v", err)
-	}
-	defer func() { _ = ln.Close() }()
+	// Drop the connection once the login line is in.
+	srv := newFakeServer(t, func(sc *serverConn) { _ = sc.Close() })
 
-	go func() {
-		conn, err := ln.Accept()
-		if err != nil {
-			return
-		}
-		// Consume the login line, then drop the connection.
-		buf := make([]byte, 256)
-		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
-		_, _ = conn.Read(buf)
-		_ = conn.Close()
-	}()
-
-	addr := ln.Addr().(*net.TCPAddr)
-	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", addr.Port,
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
 		WithRetryTimes(0))
 	if err := c.Connect(); err != nil {
 		t.Fatalf("connect: %v", err)
@@ -122,3 +118,1294 @@ func TestWaitReturnsAfterDropNoRetry(t *testing.T) {
 		t.Fatal("Wait() did not return after the link dropped with WithRetryTimes(0)")
 	}
 }
+
+// fakeClock is a manually advanced clock for timing-dependent tests.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	sleeps  []time.Duration
+}
+
+// fakeTicker fires on its channel whenever the owning fakeClock is advanced
+// past its next deadline.
+type fakeTicker struct {
+	clock   *fakeClock
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) NewTicker(d time.Duration) ticker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{clock: f, c: make(chan time.Time, 1), period: d, next: f.now.Add(d)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// After fires immediately, only recording d; tests drive time explicitly
+// with Advance.
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sleeps = append(f.sleeps, d)
+	c := make(chan time.Time, 1)
+	c <- f.now.Add(d)
+	return c
+}
+
+// Sleeps returns the durations passed to After so far.
+func (f *fakeClock) Sleeps() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.sleeps)
+}
+
+// Advance moves the clock forward by d, firing every ticker that became due.
+// Like time.Ticker, a tick is dropped if the previous one was not consumed.
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for _, t := range f.tickers {
+		if t.stopped {
+			continue
+		}
+		for !t.next.After(f.now) {
+			select {
+			case t.c <- f.now:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// tickerCount reports how many tickers have been created.
+func (f *fakeClock) tickerCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.tickers)
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+}
+
+// waitFor polls cond until it holds or the timeout expires.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fakeBanner is the software banner fakeServer connections greet with.
+const fakeBanner = "# aprsc 2.1.19"
+
+// fakeServer is a local APRS-IS server for client tests. It accepts every
+// connection on a loopback port, reads the client's login line and passes
+// the connection to handle, in a goroutine of its own. A connection stays
+// open after handle returns, until the test ends, unless handle closes it.
+type fakeServer struct {
+	ln       net.Listener
+	handle   func(*serverConn)
+	accepted atomic.Int32
+	done     chan struct{}
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+// serverConn is a client connection accepted by a fakeServer.
+type serverConn struct {
+	net.Conn
+	r     *bufio.Reader
+	srv   *fakeServer
+	Login string // the client's login line, without CR/LF
+}
+
+// newFakeServer starts a fakeServer on a free loopback port. handle may be
+// nil to just accept connections.
+func newFakeServer(t *testing.T, handle func(*serverConn)) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	return serveFakeServer(t, ln, handle)
+}
+
+// serveFakeServer runs a fakeServer on ln; it is shut down when the test
+// ends.
+func serveFakeServer(t *testing.T, ln net.Listener, handle func(*serverConn)) *fakeServer {
+	s := &fakeServer{ln: ln, handle: handle, done: make(chan struct{})}
+	t.Cleanup(func() {
+		_ = ln.Close()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.done)
+		for _, conn := range s.conns {
+			_ = conn.Close()
+		}
+	})
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.accepted.Add(1)
+		s.mu.Lock()
+		select {
+		case <-s.done:
+			_ = conn.Close()
+		default:
+			s.conns = append(s.conns, conn)
+			go s.serveConn(conn)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *fakeServer) serveConn(conn net.Conn) {
+	c := &serverConn{Conn: conn, r: bufio.NewReader(conn), srv: s}
+	login, err := c.r.ReadString('\n')
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	c.Login = strings.TrimRight(login, "\r\n")
+	if s.handle != nil {
+		s.handle(c)
+	}
+}
+
+// Port returns the port the server listens on.
+func (s *fakeServer) Port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+// Accepted returns how many connections the server has accepted.
+func (s *fakeServer) Accepted() int {
+	return int(s.accepted.Load())
+}
+
+// StopListening closes the listening socket, so that further connection
+// attempts are refused; accepted connections are kept.
+func (s *fakeServer) StopListening() {
+	_ = s.ln.Close()
+}
+
+// Send writes lines to the client, each terminated by CRLF, in one write.
+func (c *serverConn) Send(lines ...string) {
+	_, _ = c.Write([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+}
+
+// Greet sends the server banner and a logresp line for the login's
+// callsign.
+func (c *serverConn) Greet(verified bool) {
+	call, status := "N0CALL", "unverified"
+	if f := strings.Fields(c.Login); len(f) > 1 {
+		call = f[1]
+	}
+	if verified {
+		status = "verified"
+	}
+	c.Send(fakeBanner, "# logresp "+call+" "+status+", server T2TEST")
+}
+
+// ReadLine reads the next line from the client, without CR/LF.
+func (c *serverConn) ReadLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// Read reads from the client through the buffer the login was read with.
+func (c *serverConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// TestFakeClockDrivesStatsAndHeartbeat advances a fake clock to trigger the
+// stats window and the 5-minute heartbeat without waiting in real time.
+func TestFakeClockDrivesStatsAndHeartbeat(t *testing.T) {
+	lines := make(chan string, 4)
+	srv := newFakeServer(t, func(sc *serverConn) {
+		lines <- sc.Login
+		sc.Send("N0CALL>APRS:>hello")
+		for {
+			line, err := sc.ReadLine()
+			if err != nil {
+				return
+			}
+			lines <- strings.TrimSpace(line)
+		}
+	})
+
+	fc := newFakeClock()
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), WithHandler(func(string) {}), withClock(fc))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	// Login line first.
+	select {
+	case line := <-lines:
+		if !strings.HasPrefix(line, "user N0CALL") {
+			t.Fatalf("first line = %q, want login", line)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no login line received")
+	}
+
+	// Stats updater and heartbeat tickers are running.
+	waitFor(t, "background tickers", func() bool { return fc.tickerCount() >= 2 })
+
+	// One stats window after receiving data yields a non-zero receive rate.
+	waitFor(t, "received bytes", func() bool { return c.GetStats().TotalRecvBytes > 0 })
+	fc.Advance(time.Second)
+	waitFor(t, "receive rate", func() bool { return c.GetStats().CurrentRecvRate > 0 })
+
+	// Five minutes later the heartbeat fires.
+	fc.Advance(5 * time.Minute)
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, "keepalive") {
+			t.Errorf("heartbeat line = %q, want keepalive", line)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("heartbeat not sent after advancing the clock")
+	}
+
+	if got := c.GetStats().LastActivity; !got.After(fc.Now().Add(-time.Second)) {
+		t.Errorf("LastActivity = %v, want fake-clock time near %v", got, fc.Now())
+	}
+}
+
+// TestSendInfoHeader verifies SendInfo assembles the packet header from the
+// client's callsign and the configured TX path.
+func TestSendInfoHeader(t *testing.T) {
+	c := NewClient("N0CALL-9", "", Fullfeed, TCP, "127.0.0.1", 14580)
+	if got, want := c.buildPacket(">hello"), "N0CALL-9>APRS,TCPIP*:>hello"; got != want {
+		t.Errorf("default header: got %q, want %q", got, want)
+	}
+
+	path := []string{"WIDE1-1", "WIDE2-1"}
+	c = NewClient("N0CALL-9", "", Fullfeed, TCP, "127.0.0.1", 14580,
+		WithTXPath("APZ001", path))
+	path[1] = "WIDE7-7" // the client keeps its own copy
+	if got, want := c.buildPacket("!4903.50N/07201.75W>"), "N0CALL-9>APZ001,WIDE1-1,WIDE2-1:!4903.50N/07201.75W>"; got != want {
+		t.Errorf("custom header: got %q, want %q", got, want)
+	}
+
+	c = NewClient("N0CALL-9", "", Fullfeed, TCP, "127.0.0.1", 14580, WithTXPath("APRS", nil))
+	if got, want := c.buildPacket(">x"), "N0CALL-9>APRS:>x"; got != want {
+		t.Errorf("empty path: got %q, want %q", got, want)
+	}
+}
+
+// TestConnectionTypeVerifiedIGate verifies a verified igate login maps to
+// qConstruct.ConnectionVerified, and that the server's logresp overrides the
+// passcode-based assumption.
+func TestConnectionTypeVerifiedIGate(t *testing.T) {
+	c := NewClient("N0CALL", "-1", IGate, TCP, "127.0.0.1", 14580)
+	if got := c.ConnectionType(); got != qConstruct.ConnectionUnverified {
+		t.Errorf("bad passcode: ConnectionType() = %v, want ConnectionUnverified", got)
+	}
+	c = NewClient("N0CALL", "", IGate, UDP, "127.0.0.1", 8080)
+	if got := c.ConnectionType(); got != qConstruct.ConnectionDirectUDP {
+		t.Errorf("udp: ConnectionType() = %v, want ConnectionDirectUDP", got)
+	}
+
+	pass := aprsutils.PasscodeString("N0CALL")
+	c = NewClient("N0CALL", pass, IGate, TCP, "127.0.0.1", 8080, WithSendOnlyPort())
+	if got := c.ConnectionType(); got != qConstruct.ConnectionSendOnly {
+		t.Errorf("send-only: ConnectionType() = %v, want ConnectionSendOnly", got)
+	}
+	c = NewClient("N0CALL", pass, IGate, TCP, "127.0.0.1", 14580, WithClientOnlyPort())
+	if got := c.ConnectionType(); got != qConstruct.ConnectionClientOnly {
+		t.Errorf("client-only: ConnectionType() = %v, want ConnectionClientOnly", got)
+	}
+	c = NewClient("N0CALL", "-1", IGate, TCP, "127.0.0.1", 8080, WithSendOnlyPort())
+	if got := c.ConnectionType(); got != qConstruct.ConnectionUnverified {
+		t.Errorf("unverified send-only: ConnectionType() = %v, want ConnectionUnverified", got)
+	}
+
+	srv := newFakeServer(t, func(sc *serverConn) { sc.Greet(true) })
+
+	// The passcode is wrong, so only the logresp can make this verified.
+	c = NewClient("N0CALL", "12345", IGate, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	waitFor(t, "verified logresp", c.Verified)
+	if got := c.ConnectionType(); got != qConstruct.ConnectionVerified {
+		t.Errorf("ConnectionType() = %v, want ConnectionVerified", got)
+	}
+}
+
+// TestReceiveLineWithNUL verifies a line containing NUL and BEL bytes reaches
+// the handler whole rather than being cut at the NUL.
+func TestReceiveLineWithNUL(t *testing.T) {
+	const pkt = "N0CALL>APRS:>a\x00b\x07c"
+	srv := newFakeServer(t, func(sc *serverConn) { sc.Send(pkt, "N0CALL>APRS:>next") })
+
+	got := make(chan string, 2)
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), WithHandler(func(packet string) { got <- packet }))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	for _, want := range []string{pkt, "N0CALL>APRS:>next"} {
+		select {
+		case line := <-got:
+			if line != want {
+				t.Errorf("handler got %q, want %q", line, want)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
+
+// TestSendRawNoFraming verifies SendRaw writes exactly the given bytes, while
+// SendPacket still appends CRLF.
+func TestSendRawNoFraming(t *testing.T) {
+	received := make(chan string, 1)
+	srv := newFakeServer(t, func(sc *serverConn) {
+		_ = sc.SetReadDeadline(time.Now().Add(3 * time.Second))
+		var all []byte
+		buf := make([]byte, 256)
+		for !strings.HasSuffix(string(all), "END\r\n") {
+			n, err := sc.Read(buf)
+			if err != nil {
+				break
+			}
+			all = append(all, buf[:n]...)
+		}
+		received <- string(all)
+	})
+
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.SendRaw([]byte("N0CALL>APRS:>one\n")); err != nil {
+		t.Fatalf("SendRaw: %v", err)
+	}
+	if err := c.SendRaw([]byte("N0CALL>APRS:>tw")); err != nil {
+		t.Fatalf("SendRaw: %v", err)
+	}
+	if err := c.SendPacket("o END"); err != nil {
+		t.Fatalf("SendPacket: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if want := "N0CALL>APRS:>one\nN0CALL>APRS:>two END\r\n"; got != want {
+			t.Errorf("stream = %q, want %q", got, want)
+		}
+	case <-time.After(4 * time.Second):
+		t.Fatal("timed out waiting for data")
+	}
+}
+
+// TestHandlerPanicRecovered verifies a panicking handler is reported to the
+// panic callback and the client keeps receiving.
+func TestHandlerPanicRecovered(t *testing.T) {
+	srv := newFakeServer(t, func(sc *serverConn) { sc.Send("N0CALL>APRS:>boom", "N0CALL>APRS:>fine") })
+
+	panics := make(chan string, 1)
+	handled := make(chan string, 1)
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0),
+		WithHandler(func(packet string) {
+			if strings.HasSuffix(packet, "boom") {
+				panic("handler bug")
+			}
+			handled <- packet
+		}),
+		WithHandlerPanicHandler(func(r any, raw string) {
+			panics <- raw
+		}),
+	)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	select {
+	case raw := <-panics:
+		if raw != "N0CALL>APRS:>boom" {
+			t.Errorf("panic callback raw = %q", raw)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("panic callback not called")
+	}
+	select {
+	case raw := <-handled:
+		if raw != "N0CALL>APRS:>fine" {
+			t.Errorf("handled %q after panic", raw)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("client stopped receiving after handler panic")
+	}
+	if !c.Up() {
+		t.Error("client is down after handler panic")
+	}
+}
+
+func TestDefaultPort(t *testing.T) {
+	cases := []struct {
+		mode Mode
+		tls  bool
+		want int
+	}{
+		{Fullfeed, false, 10152},
+		{Fullfeed, true, 0},
+		{IGate, false, 14580},
+		{IGate, true, 24580},
+	}
+	for _, c := range cases {
+		if got := DefaultPort(c.mode, c.tls); got != c.want {
+			t.Errorf("DefaultPort(%s, %v) = %d, want %d", c.mode, c.tls, got, c.want)
+		}
+	}
+
+	if p := NewClient("N0CALL", "", IGate, TCP, "localhost", 0).Port(); p != PortFiltered {
+		t.Errorf("NewClient TCP port 0 -> %d, want %d", p, PortFiltered)
+	}
+	if p := NewClient("N0CALL", "", IGate, UDP, "localhost", 0).Port(); p != PortUDPSubmit {
+		t.Errorf("NewClient UDP port 0 -> %d, want %d", p, PortUDPSubmit)
+	}
+}
+
+func TestResolveServer(t *testing.T) {
+	cases := []struct {
+		addr     string
+		mode     Mode
+		tls      bool
+		host     string
+		port     int
+		wantFail bool
+	}{
+		{"rotate.aprs.net", IGate, false, "rotate.aprs.net", 14580, false},
+		{"rotate.aprs.net", IGate, true, "rotate.aprs.net", 24580, false},
+		{"rotate.aprs.net", Fullfeed, false, "rotate.aprs.net", 10152, false},
+		{"rotate.aprs.net", Fullfeed, true, "", 0, true},
+		{"[2001:db8::1]:14580", IGate, false, "2001:db8::1", 14580, false},
+		{"example.org:20152", Fullfeed, false, "example.org", 20152, false},
+		{"example.org:10152", IGate, false, "", 0, true},
+		{"example.org:14580", Fullfeed, false, "", 0, true},
+		{"example.org:0", IGate, false, "", 0, true},
+		{"example.org:port", IGate, false, "", 0, true},
+	}
+	for _, c := range cases {
+		host, port, err := ResolveServer(c.addr, c.mode, c.tls)
+		if (err != nil) != c.wantFail || host != c.host || port != c.port {
+			t.Errorf("ResolveServer(%q, %s, %v) = %q, %d, %v", c.addr, c.mode, c.tls, host, port, err)
+		}
+	}
+	if _, _, err := ResolveServer("example.org:10152", IGate, false); !errors.Is(err, ErrPortModeMismatch) {
+		t.Errorf("mode mismatch error = %v, want ErrPortModeMismatch", err)
+	}
+}
+
+// TestLoginHandlerVerifiedMidSession verifies the login callback fires for
+// each logresp, following an unverified login that turns verified.
+func TestLoginHandlerVerifiedMidSession(t *testing.T) {
+	srv := newFakeServer(t, func(sc *serverConn) {
+		sc.Greet(false)
+		time.Sleep(100 * time.Millisecond)
+		sc.Send("# logresp N0CALL verified, server T2TEST")
+	})
+
+	var mu sync.Mutex
+	var states []bool
+	var server string
+	c := NewClient("N0CALL", "-1", IGate, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0),
+		WithLoginHandler(func(verified bool, serverID string) {
+			mu.Lock()
+			defer mu.Unlock()
+			states = append(states, verified)
+			server = serverID
+		}),
+	)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	waitFor(t, "second logresp", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(states) == 2
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if !c.Verified() || len(states) != 2 || states[0] || !states[1] || server != "T2TEST" {
+		t.Errorf("login callbacks = %v (server %q), want [false true] from T2TEST", states, server)
+	}
+	if c.ServerID() != "T2TEST" {
+		t.Errorf("ServerID() = %q, want T2TEST", c.ServerID())
+	}
+}
+
+// TestEventChannel verifies connect, login and disconnect events are emitted
+// over a connection's life, and that a full channel drops and counts events.
+func TestEventChannel(t *testing.T) {
+	srv := newFakeServer(t, func(sc *serverConn) {
+		sc.Greet(true)
+		time.Sleep(100 * time.Millisecond)
+		_ = sc.Close()
+	})
+
+	events := make(chan Event, 16)
+	c := NewClient("N0CALL", "-1", IGate, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0),
+		WithEventChannel(events),
+	)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+	c.Wait()
+
+	var got []EventType
+	for len(events) > 0 {
+		ev := <-events
+		got = append(got, ev.Type)
+		if ev.Time.IsZero() || ev.Address != c.address() {
+			t.Errorf("event %+v lacks time or address", ev)
+		}
+		if ev.Type == EventLogin && (!ev.Verified || ev.ServerID != "T2TEST") {
+			t.Errorf("login event = %+v", ev)
+		}
+	}
+	want := []EventType{EventConnect, EventLogin, EventDisconnect}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	// A packet that fails to parse is reported, and closing the client on
+	// purpose reports a disconnect without an error.
+	srv2 := newFakeServer(t, func(sc *serverConn) { sc.Send(fakeBanner, "N0CALL>APRS") })
+	events = make(chan Event, 16)
+	c = NewClient("N0CALL", "-1", IGate, TCP, "127.0.0.1", srv2.Port(),
+		WithRetryTimes(0),
+		WithEventChannel(events),
+	)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	var parseErr Event
+	for ev := range events {
+		if ev.Type == EventParseError {
+			parseErr = ev
+			break
+		}
+	}
+	if parseErr.Packet != "N0CALL>APRS" || parseErr.Count != 1 || parseErr.Error == "" {
+		t.Errorf("parse-error event = %+v", parseErr)
+	}
+	c.Close()
+	c.Wait()
+	waitFor(t, "disconnect event", func() bool { return len(events) > 0 })
+	if ev := <-events; ev.Type != EventDisconnect || ev.Error != "" {
+		t.Errorf("event after Close = %+v, want a disconnect without error", ev)
+	}
+
+	// Nobody reads an unbuffered channel: events are dropped, not blocking.
+	c = NewClient("N0CALL", "", IGate, UDP, "127.0.0.1", 8080, WithEventChannel(make(chan Event)))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("udp connect: %v", err)
+	}
+	c.Close()
+	if d := c.GetStats().EventsDropped; d != 2 {
+		t.Errorf("EventsDropped = %d, want 2", d)
+	}
+}
+
+func TestServerRejection(t *testing.T) {
+	cases := []struct {
+		name  string
+		line  string
+		want  error
+		conns int // connections the client makes with WithRetryTimes(2)
+	}{
+		{"port full", "# Port full.", ErrServerFull, 3},
+		{"bad login", "# Bad login, closing connection", ErrLoginRejected, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newFakeServer(t, func(sc *serverConn) { sc.Send(fakeBanner, tc.line) })
+
+			events := make(chan Event, 32)
+			c := NewClient("N0CALL", "-1", IGate, TCP, "127.0.0.1", srv.Port(),
+				WithRetryTimes(2),
+				WithEventChannel(events),
+				withClock(newFakeClock()),
+			)
+			if err := c.Connect(); err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			defer c.Close()
+
+			done := make(chan struct{})
+			go func() {
+				c.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Wait() did not return after the server rejected the client")
+			}
+
+			if err := c.Rejection(); !errors.Is(err, tc.want) {
+				t.Errorf("Rejection() = %v, want %v", err, tc.want)
+			}
+			if n := srv.Accepted(); n != tc.conns {
+				t.Errorf("connections = %d, want %d", n, tc.conns)
+			}
+			rejected := 0
+			for len(events) > 0 {
+				if ev := <-events; ev.Type == EventRejected {
+					rejected++
+				}
+			}
+			if rejected != tc.conns {
+				t.Errorf("rejected events = %d, want %d", rejected, tc.conns)
+			}
+		})
+	}
+}
+
+func TestHeardList(t *testing.T) {
+	batches := make(chan string)
+	srv := newFakeServer(t, func(sc *serverConn) {
+		for b := range batches {
+			_, _ = sc.Write([]byte(b))
+		}
+	})
+
+	fc := newFakeClock()
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), withClock(fc), WithHeardList(10*time.Minute, 3))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+	send := func(lines string, total uint64) {
+		batches <- lines
+		waitFor(t, "packets", func() bool { return c.GetStats().PacketsReceived >= total })
+	}
+
+	send("K1ABC-9>APRS:!4903.50N/07201.75W>Mobile\r\n"+
+		"W2XYZ>APRS:>status\r\n", 2)
+	fc.Advance(4 * time.Minute)
+	send("k1abc-9>APRS:>no position\r\n"+
+		"w2xyz-0>APRS:;OBJ      *111111z4903.50N/07201.75W-\r\n", 4)
+
+	got := c.HeardStations()
+	if len(got) != 2 || got[0].Callsign != "K1ABC-9" || got[1].Callsign != "W2XYZ" {
+		t.Fatalf("heard = %+v", got)
+	}
+	k := got[0]
+	if k.Packets != 2 || !k.LastHeard.Equal(fc.Now()) || !k.HasPosition || !approxEqual(k.Lat, 49.058333) {
+		t.Errorf("K1ABC-9 = %+v", k)
+	}
+	// The object's position is not W2XYZ's own.
+	if w := got[1]; w.Packets != 2 || w.HasPosition {
+		t.Errorf("W2XYZ = %+v", w)
+	}
+
+	// Two more stations: the list holds 3, so the one heard longest ago goes.
+	fc.Advance(time.Minute)
+	send("N3AAA>APRS:>a\r\n", 5)
+	fc.Advance(time.Minute)
+	send("N3BBB>APRS:>b\r\n", 6)
+	got = c.HeardStations()
+	if len(got) != 3 || got[0].Callsign != "N3BBB" || got[1].Callsign != "N3AAA" {
+		t.Fatalf("heard after eviction = %+v", got)
+	}
+
+	// Ten minutes after K1ABC-9 and W2XYZ were last heard, they expire.
+	fc.Advance(9 * time.Minute)
+	got = c.HeardStations()
+	if len(got) != 2 || got[0].Callsign != "N3BBB" || got[1].Callsign != "N3AAA" {
+		t.Errorf("heard after expiry = %+v", got)
+	}
+	close(batches)
+}
+
+func approxEqual(a, b float64) bool {
+	return a-b < 1e-6 && b-a < 1e-6
+}
+
+func TestResolveRotate(t *testing.T) {
+	defer func(orig func(context.Context, string) ([]net.IPAddr, error)) { lookupIPAddr = orig }(lookupIPAddr)
+
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "rotate.aprs2.net":
+			return []net.IPAddr{
+				{IP: net.ParseIP("192.0.2.10")},
+				{IP: net.ParseIP("2001:db8::10")},
+				{IP: net.ParseIP("192.0.2.20")},
+				{IP: net.ParseIP("192.0.2.10")},
+			}, nil
+		case "empty.aprs2.net":
+			return nil, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	servers, err := ResolveRotate("rotate.aprs2.net")
+	if err != nil {
+		t.Fatalf("ResolveRotate: %v", err)
+	}
+	var got []string
+	for _, s := range servers {
+		if s.Hostname != "rotate.aprs2.net" {
+			t.Errorf("Hostname = %q", s.Hostname)
+		}
+		got = append(got, s.Address(PortFiltered))
+	}
+	want := []string{"192.0.2.10:14580", "[2001:db8::10]:14580", "192.0.2.20:14580"}
+	if !slices.Equal(got, want) {
+		t.Errorf("servers = %v, want %v", got, want)
+	}
+
+	if _, err := ResolveRotate("missing.aprs2.net"); err == nil {
+		t.Error("failed lookup should return an error")
+	} else if dnsErr, ok := errors.AsType[*net.DNSError](err); !ok || !dnsErr.IsNotFound {
+		t.Errorf("error %v does not wrap the DNS error", err)
+	}
+	if _, err := ResolveRotate("empty.aprs2.net"); err == nil {
+		t.Error("lookup without addresses should return an error")
+	}
+}
+
+func TestReceivedHandler(t *testing.T) {
+	srv := newFakeServer(t, func(sc *serverConn) {
+		sc.Send("K1ABC>APRS:!4903.50N/07201.75W>Mobile", "K1ABC>APRS:!garbage")
+	})
+
+	fc := newFakeClock()
+	got := make(chan ReceivedPacket, 2)
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), withClock(fc), WithHandler(func(string) {}),
+		WithReceivedHandler(func(rp ReceivedPacket) { got <- rp }))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	for i, want := range []string{"K1ABC>APRS:!4903.50N/07201.75W>Mobile", "K1ABC>APRS:!garbage"} {
+		select {
+		case rp := <-got:
+			if rp.Raw != want || !rp.ReceivedAt.Equal(fc.Now()) || rp.Parsed == nil || rp.Parsed.From != "K1ABC" {
+				t.Errorf("packet %d = %+v", i, rp)
+			}
+			if (rp.ParseErr != nil) != (i == 1) {
+				t.Errorf("packet %d: ParseErr = %v", i, rp.ParseErr)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("packet %d not delivered", i)
+		}
+	}
+}
+
+func TestPacketsChannel(t *testing.T) {
+	want := []string{"K1ABC>APRS:>one", "K1ABC>APRS:>two", "K1ABC>APRS:>three"}
+	srv := newFakeServer(t, func(sc *serverConn) { sc.Send(want...) })
+
+	var handled atomic.Int32
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), WithHandler(func(string) { handled.Add(1) }))
+	packets := c.Packets()
+	if c.Packets() != packets {
+		t.Fatal("Packets returned a different channel on the second call")
+	}
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	for i, w := range want {
+		select {
+		case got := <-packets:
+			if got != w {
+				t.Errorf("packet %d = %q, want %q", i, got, w)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("packet %d not delivered", i)
+		}
+	}
+	waitFor(t, "handler to see every packet", func() bool { return handled.Load() == 3 })
+
+	c.Close()
+	select {
+	case _, ok := <-packets:
+		if ok {
+			t.Error("received a packet after Close")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Packets channel not closed by Close")
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	// Serve one connection, then go away so every reconnect is refused.
+	srv := newFakeServer(t, func(sc *serverConn) {
+		sc.srv.StopListening()
+		_ = sc.Close()
+	})
+
+	fc := newFakeClock()
+	events := make(chan Event, 32)
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(4), withClock(fc), WithEventChannel(events),
+		WithReconnectBackoff(100*time.Millisecond, 500*time.Millisecond, 2))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.WaitContext(ctx); err != nil {
+		t.Fatalf("client did not give up: %v", err)
+	}
+
+	// Nominal delays 100, 200, 400 and (capped) 500ms, each jittered into
+	// [d/2, d].
+	nominal := []time.Duration{100, 200, 400, 500}
+	sleeps := fc.Sleeps()
+	if len(sleeps) != len(nominal) {
+		t.Fatalf("sleeps = %v, want %d", sleeps, len(nominal))
+	}
+	for i, d := range sleeps {
+		want := nominal[i] * time.Millisecond
+		if d < want/2 || d > want {
+			t.Errorf("delay %d = %v, want within [%v, %v]", i, d, want/2, want)
+		}
+		// Doubling delays never shrink; once capped they may.
+		if i > 0 && nominal[i] >= 2*nominal[i-1] && d < sleeps[i-1] {
+			t.Errorf("delay %d = %v shrank from %v", i, d, sleeps[i-1])
+		}
+	}
+	if sleeps[3] <= sleeps[0] {
+		t.Errorf("delays did not grow: %v", sleeps)
+	}
+
+	var reconnects int
+	var gaveUp *Event
+	for len(events) > 0 {
+		ev := <-events
+		switch ev.Type {
+		case EventReconnect:
+			reconnects++
+		case EventGaveUp:
+			gaveUp = &ev
+		}
+	}
+	if reconnects != 4 || gaveUp == nil || gaveUp.Error == "" {
+		t.Errorf("reconnects = %d, gave-up event = %+v", reconnects, gaveUp)
+	}
+}
+
+// TestReconnectOutlivesConnectContext checks that cancelling the context
+// given to ConnectContext, as a deferred cancel does, does not stop the client
+// from reconnecting later.
+func TestReconnectOutlivesConnectContext(t *testing.T) {
+	// Drop the first connection after the login; hold the second one open.
+	srv := newFakeServer(t, func(sc *serverConn) {
+		if sc.srv.Accepted() == 1 {
+			_ = sc.Close()
+		}
+	})
+
+	events := make(chan Event, 32)
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(3), withClock(newFakeClock()), WithEventChannel(events))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := c.ConnectContext(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	cancel()
+	defer c.Close()
+
+	var connects int
+	timeout := time.After(3 * time.Second)
+	for connects < 2 {
+		select {
+		case ev := <-events:
+			if ev.Type == EventConnect {
+				connects++
+			}
+		case <-timeout:
+			t.Fatalf("connects = %d, want a reconnect after the context was cancelled", connects)
+		}
+	}
+}
+
+func TestFormatCounts(t *testing.T) {
+	srv := newFakeServer(t, func(sc *serverConn) {
+		sc.Send("K1ABC>APRS:!4903.50N/07201.75W>Mobile",
+			"K1ABC>APRS:!4903.50N/07201.75W>again",
+			"W2XYZ>APRS::K1ABC    :hello{1",
+			"OX8AAA>T7UU97:`(T4l!u>/]",
+			"W2XYZ>APRS:;OBJ      *111111z4903.50N/07201.75W-",
+			"K1ABC>APRS:!garbage")
+	})
+
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), WithFormatCounts(), WithHandler(func(string) {}))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+	waitFor(t, "packets", func() bool { return c.GetStats().PacketsReceived >= 6 })
+
+	want := map[string]uint64{"uncompressed": 2, "message": 1, "mic-e": 1, "object": 1, FormatError: 1}
+	if got := c.FormatCounts(); !maps.Equal(got, want) {
+		t.Errorf("FormatCounts = %v, want %v", got, want)
+	}
+
+	c.ResetFormatCounts()
+	if got := c.FormatCounts(); len(got) != 0 {
+		t.Errorf("after reset FormatCounts = %v", got)
+	}
+
+	if got := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", 0).FormatCounts(); got != nil {
+		t.Errorf("disabled FormatCounts = %v, want nil", got)
+	}
+}
+
+func TestAutoPasscode(t *testing.T) {
+	want := aprsutils.PasscodeString("K1ABC")
+	c := NewClient("K1ABC-9", "", IGate, TCP, "127.0.0.1", 0, WithAutoPasscode())
+	if line := c.loginLine(); !strings.Contains(line, " pass "+want+" ") {
+		t.Errorf("login line = %q, want pass %s", line, want)
+	}
+	if !c.Verified() {
+		t.Error("auto passcode not treated as verified")
+	}
+
+	// An explicit passcode wins, and N0CALL gets none.
+	if line := NewClient("K1ABC", "-1", IGate, TCP, "127.0.0.1", 0, WithAutoPasscode()).loginLine(); !strings.Contains(line, " pass -1 ") {
+		t.Errorf("explicit passcode overridden: %q", line)
+	}
+	if line := NewClient("", "", IGate, TCP, "127.0.0.1", 0, WithAutoPasscode()).loginLine(); strings.Contains(line, " pass ") {
+		t.Errorf("N0CALL got a passcode: %q", line)
+	}
+}
+
+func TestSetFilter(t *testing.T) {
+	received := make(chan string, 2)
+	srv := newFakeServer(t, func(sc *serverConn) {
+		received <- sc.Login
+		if line, err := sc.ReadLine(); err == nil {
+			received <- line
+		}
+	})
+
+	c := NewClient("N0CALL", "", IGate, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), WithFilter("r/33/-96/100"))
+	if err := c.SetFilter("r/40/-100/50"); err == nil {
+		t.Error("SetFilter before Connect succeeded")
+	}
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	if login := <-received; !strings.HasSuffix(login, " filter r/33/-96/100") {
+		t.Errorf("login = %q", login)
+	}
+	if err := c.SetFilter("r/40/-100/50 t/p"); err != nil {
+		t.Fatalf("SetFilter: %v", err)
+	}
+	select {
+	case got := <-received:
+		if got != "#filter r/40/-100/50 t/p" {
+			t.Errorf("command = %q", got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("filter command not received")
+	}
+	if got := c.Filter(); got != "r/40/-100/50 t/p" {
+		t.Errorf("Filter() = %q", got)
+	}
+	if err := c.SetFilter("r/1/2/3\r\nN0CALL>APRS:>injected"); err == nil {
+		t.Error("SetFilter accepted a line break")
+	}
+}
+
+func TestSetFilterFullfeed(t *testing.T) {
+	srv := newFakeServer(t, nil)
+
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(), WithRetryTimes(0))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+	if err := c.SetFilter("r/40/-100/50"); err == nil {
+		t.Error("SetFilter on a full feed client succeeded")
+	}
+}
+
+func TestRawBuffer(t *testing.T) {
+	batches := make(chan string)
+	srv := newFakeServer(t, func(sc *serverConn) {
+		for b := range batches {
+			_, _ = sc.Write([]byte(b))
+		}
+	})
+
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), WithRawBuffer(3), WithHandler(func(string) {}))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+	send := func(lines string, total uint64) {
+		batches <- lines
+		waitFor(t, "packets", func() bool { return c.GetStats().PacketsReceived >= total })
+	}
+
+	send("K1ABC>APRS:>1\r\nK1ABC>APRS:>2\r\n", 2)
+	if got, want := c.RecentRaw(), []string{"K1ABC>APRS:>1", "K1ABC>APRS:>2"}; !slices.Equal(got, want) {
+		t.Errorf("partly filled: RecentRaw = %q, want %q", got, want)
+	}
+
+	// Three more lines wrap the ring past its start.
+	send("K1ABC>APRS:>3\r\nK1ABC>APRS:>4\r\nK1ABC>APRS:>5\r\n", 5)
+	if got, want := c.RecentRaw(), []string{"K1ABC>APRS:>3", "K1ABC>APRS:>4", "K1ABC>APRS:>5"}; !slices.Equal(got, want) {
+		t.Errorf("wrapped: RecentRaw = %q, want %q", got, want)
+	}
+	close(batches)
+
+	if got := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", 0).RecentRaw(); got != nil {
+		t.Errorf("disabled RecentRaw = %q, want nil", got)
+	}
+}
+
+func TestStateCallback(t *testing.T) {
+	// Drop the first connection after the login; keep the second one open.
+	srv := newFakeServer(t, func(sc *serverConn) {
+		if sc.srv.Accepted() == 1 {
+			_ = sc.Close()
+		}
+	})
+
+	type change struct{ from, to ConnectionState }
+	var mu sync.Mutex
+	var got []change
+	changes := func() []change {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(got)
+	}
+
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(3), withClock(newFakeClock()),
+		WithStateCallback(func(from, to ConnectionState) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, change{from, to})
+		}))
+	if c.State() != Disconnected {
+		t.Errorf("initial State() = %q", c.State())
+	}
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	waitFor(t, "reconnect", func() bool { return len(changes()) >= 5 })
+
+	want := []change{
+		{Disconnected, Connecting},
+		{Connecting, Connected},
+		{Connected, Disconnected},
+		{Disconnected, Reconnecting},
+		{Reconnecting, Connected},
+	}
+	if got := changes(); !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+
+	c.Close()
+	c.Close()
+	all := changes()
+	if last := all[len(all)-1]; last.to != Closed || c.State() != Closed {
+		t.Errorf("after Close: transitions = %v, State() = %q", all, c.State())
+	}
+	if n := len(all); n > 7 {
+		t.Errorf("too many transitions after Close: %v", all)
+	}
+}
+
+func TestLastError(t *testing.T) {
+	// Reserve a port, then free it so dialling it is refused.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", ln.Addr().(*net.TCPAddr).Port, WithRetryTimes(0))
+	defer c.Close()
+	if c.LastError() != nil {
+		t.Errorf("LastError before Connect = %v", c.LastError())
+	}
+	dialErr := c.Connect()
+	if dialErr == nil {
+		t.Fatal("Connect to a closed port succeeded")
+	}
+	last := c.LastError()
+	if !errors.Is(last, dialErr) || !strings.Contains(last.Error(), addr) {
+		t.Errorf("LastError = %v, want it to wrap %v", last, dialErr)
+	}
+	if _, ok := errors.AsType[*net.OpError](last); !ok {
+		t.Errorf("LastError = %v, want a wrapped *net.OpError", last)
+	}
+
+	// A successful connect clears it.
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("cannot listen on %s again: %v", addr, err)
+	}
+	serveFakeServer(t, ln, nil)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := c.LastError(); err != nil {
+		t.Errorf("LastError after a successful connect = %v", err)
+	}
+}
+
+func TestServerTimeout(t *testing.T) {
+	// Accept connections and never write to them.
+	srv := newFakeServer(t, nil)
+
+	events := make(chan Event, 16)
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(1), withClock(newFakeClock()), WithEventChannel(events),
+		WithServerTimeout(200*time.Millisecond))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	waitFor(t, "reconnect after the server went silent", func() bool { return srv.Accepted() == 2 })
+
+	var disconnect *Event
+	for len(events) > 0 {
+		if ev := <-events; ev.Type == EventDisconnect && disconnect == nil {
+			disconnect = &ev
+		}
+	}
+	if disconnect == nil || disconnect.Error != ErrServerTimeout.Error() {
+		t.Errorf("disconnect event = %+v, want error %q", disconnect, ErrServerTimeout)
+	}
+}
+
+// TestServerTimeoutShorterThanTwoReads checks that a silent server is noticed
+// at the server timeout, not at the next read deadline after it.
+func TestServerTimeoutShorterThanTwoReads(t *testing.T) {
+	srv := newFakeServer(t, func(sc *serverConn) { _, _ = io.Copy(io.Discard, sc) })
+
+	events := make(chan Event, 16)
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), WithEventChannel(events),
+		WithReadTimeout(500*time.Millisecond), WithServerTimeout(600*time.Millisecond))
+	start := time.Now()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != EventDisconnect {
+				continue
+			}
+			// Two 500ms reads would only notice it after a second.
+			if elapsed := time.Since(start); ev.Error != ErrServerTimeout.Error() || elapsed >= 900*time.Millisecond {
+				t.Errorf("disconnect after %v with %q, want %q after about 600ms", elapsed, ev.Error, ErrServerTimeout)
+			}
+			return
+		case <-timeout:
+			t.Fatal("no disconnect from the silent server")
+		}
+	}
+}
+
+func TestParsedHandler(t *testing.T) {
+	srv := newFakeServer(t, func(sc *serverConn) {
+		sc.Send("K1ABC-9>APRS,WIDE1-1,qAR,N0IGT:!4903.50N/07201.75W>Mobile")
+	})
+
+	type result struct {
+		p   parser.Parsed
+		err error
+	}
+	got := make(chan result, 1)
+	c := NewClient("N0CALL", "", Fullfeed, TCP, "127.0.0.1", srv.Port(),
+		WithRetryTimes(0), WithParsedHandler(func(p parser.Parsed, err error) { got <- result{p, err} }))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer c.Close()
+
+	select {
+	case r := <-got:
+		if r.err != nil {
+			t.Fatalf("parse error: %v", r.err)
+		}
+		if r.p.From != "K1ABC-9" || !approxEqual(r.p.Lat, 49.058333) || !approxEqual(r.p.Lon, -72.029167) || r.p.Comment != "Mobile" {
+			t.Errorf("parsed = %s %f %f %q", r.p.From, r.p.Lat, r.p.Lon, r.p.Comment)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("parsed packet not delivered")
+	}
+}
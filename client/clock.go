@@ -0,0 +1,42 @@
+package client
+
+import "time"
+
+// clock abstracts the time source used by the client so timing-dependent
+// logic (heartbeat, stats window, reconnect backoff) can be driven
+// deterministically in tests. Socket deadlines always use the wall clock,
+// since the kernel enforces them against real time.
+type clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+// ticker is the subset of *time.Ticker used by the client.
+type ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock is the default clock, backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) NewTicker(d time.Duration) ticker       { return realTicker{t: time.NewTicker(d)} }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// realTicker adapts *time.Ticker to the ticker interface.
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (r realTicker) C() <-chan time.Time { return r.t.C }
+func (r realTicker) Stop()               { r.t.Stop() }
+
+// withClock replaces the client's time source. It is unexported: only tests
+// need to substitute a fake clock.
+func withClock(cl clock) Option {
+	return func(c *Client) {
+		c.clock = cl
+	}
+}
@@ -0,0 +1,66 @@
+//go:build linux
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// nonAcceptingListener returns the port of a listening socket whose accept
+// queue is full, so that further connection attempts hang in the handshake.
+func nonAcceptingListener(t *testing.T) int {
+	t.Helper()
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("socket: %v", err)
+	}
+	t.Cleanup(func() { _ = syscall.Close(fd) })
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	// A backlog of 0 holds a single pending connection on Linux.
+	if err := syscall.Listen(fd, 0); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatalf("getsockname: %v", err)
+	}
+	port := sa.(*syscall.SockaddrInet4).Port
+
+	filler, err := net.Dial("tcp", (&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}).String())
+	if err != nil {
+		t.Fatalf("fill accept queue: %v", err)
+	}
+	t.Cleanup(func() { _ = filler.Close() })
+	return port
+}
+
+func TestConnectContextCancelledMidDial(t *testing.T) {
+	port := nonAcceptingListener(t)
+	c := NewClient("N0CALL", "", IGate, TCP, "127.0.0.1", port, WithRetryTimes(0))
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := c.ConnectContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ConnectContext error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("cancelled dial returned after %v", elapsed)
+	}
+
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelWait()
+	if err := c.WaitContext(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitContext on a running client = %v, want DeadlineExceeded", err)
+	}
+}
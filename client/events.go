@@ -0,0 +1,46 @@
+package client
+
+import "time"
+
+// EventType names a client connection event.
+type EventType string
+
+const (
+	EventConnect      EventType = "connect"       // link to the server established
+	EventConnectError EventType = "connect-error" // dialling the server failed
+	EventLogin        EventType = "login"         // server answered the login (logresp)
+	EventDisconnect   EventType = "disconnect"    // link dropped or closed
+	EventReconnect    EventType = "reconnect"     // reconnection attempt starting
+	EventRejected     EventType = "rejected"      // server refused the connection or login
+	EventGaveUp       EventType = "gave-up"       // reconnection attempts exhausted; the client is done
+	EventParseError   EventType = "parse-error"   // a received packet failed to parse
+)
+
+// Event is a structured connection event delivered by WithEventChannel,
+// tagged for JSON so it can be shipped to a log aggregator as is.
+type Event struct {
+	Type     EventType `json:"type"`
+	Time     time.Time `json:"time"`
+	Address  string    `json:"address"`             // server host:port
+	Attempt  int       `json:"attempt,omitempty"`   // EventReconnect: attempt number, from 1
+	Verified bool      `json:"verified,omitempty"`  // EventLogin: login verified
+	ServerID string    `json:"server_id,omitempty"` // EventLogin: server callsign
+	Packet   string    `json:"packet,omitempty"`    // EventParseError: the raw packet
+	Count    uint64    `json:"count,omitempty"`     // EventParseError: parse errors so far
+	Error    string    `json:"error,omitempty"`     // cause, when there is one
+}
+
+// emit delivers ev to the event channel without blocking; when the channel
+// is full the event is dropped and counted in Stats.EventsDropped.
+func (c *Client) emit(ev Event) {
+	if c.events == nil {
+		return
+	}
+	ev.Time = c.clock.Now()
+	ev.Address = c.address()
+	select {
+	case c.events <- ev:
+	default:
+		c.eventsDropped.Add(1)
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"maps"
+	"sync"
+
+	"github.com/APRSCN/aprsutils/parser"
+)
+
+// FormatError is the FormatCounts key for packets that failed to parse.
+const FormatError = "error"
+
+// formatCounts counts received packets by parser.Parsed.Format. The receive
+// loop adds to it and any goroutine may read or reset it, so every access
+// holds mu.
+type formatCounts struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// WithFormatCounts makes the client count the packets it receives by format
+// ("uncompressed", "mic-e", "message", "object", ...), read with
+// FormatCounts. Each received packet is parsed for this, so leave it off when
+// the counts are not needed.
+func WithFormatCounts() Option {
+	return func(c *Client) {
+		c.formats = &formatCounts{counts: make(map[string]uint64)}
+	}
+}
+
+// FormatCounts returns a copy of the per-format packet counts since the
+// client was created or ResetFormatCounts was last called. Packets that
+// failed to parse are counted under FormatError. It returns nil if
+// WithFormatCounts is not enabled.
+func (c *Client) FormatCounts() map[string]uint64 {
+	if c.formats == nil {
+		return nil
+	}
+	c.formats.mu.Lock()
+	defer c.formats.mu.Unlock()
+	return maps.Clone(c.formats.counts)
+}
+
+// ResetFormatCounts sets every per-format packet count back to zero.
+func (c *Client) ResetFormatCounts() {
+	if c.formats == nil {
+		return
+	}
+	c.formats.mu.Lock()
+	defer c.formats.mu.Unlock()
+	clear(c.formats.counts)
+}
+
+// add counts one received packet and the outcome of parsing it.
+func (f *formatCounts) add(p *parser.Parsed, err error) {
+	format := p.Format
+	if err != nil || format == "" {
+		format = FormatError
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counts[format]++
+}
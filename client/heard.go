@@ -0,0 +1,121 @@
+package client
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/APRSCN/aprsutils"
+	"github.com/APRSCN/aprsutils/parser"
+)
+
+// HeardStation is an entry of the client's heard list (see WithHeardList).
+type HeardStation struct {
+	Callsign    string    // source callsign as aprsutils.CallsignKey
+	LastHeard   time.Time // when the last packet from the station arrived
+	Packets     int       // packets heard since the station (re)entered the list
+	HasPosition bool      // Lat/Lon hold the station's last reported position
+	Lat         float64   // decimal degrees
+	Lon         float64   // decimal degrees
+}
+
+// heardList is the client's heard list. The receive loop adds to it and any
+// goroutine may read it, so every access holds mu. order keeps the stations
+// most recently heard first, so eviction and expiry work from its back
+// without scanning the whole list.
+type heardList struct {
+	ttl time.Duration
+	max int
+
+	mu       sync.Mutex
+	stations map[string]*list.Element // of *HeardStation, in order
+	order    list.List
+}
+
+// WithHeardList makes the client keep a list of the stations it hears, read
+// with HeardStations. A station drops out ttl after it was last heard (never
+// when ttl is 0), and when more than max stations are listed the one heard
+// longest ago is evicted (no limit when max is 0). Each received packet is
+// parsed for this, so leave it off when the handler does not need it.
+func WithHeardList(ttl time.Duration, max int) Option {
+	return func(c *Client) {
+		c.heard = &heardList{ttl: ttl, max: max, stations: make(map[string]*list.Element)}
+	}
+}
+
+// HeardStations returns the stations heard within the WithHeardList TTL, most
+// recently heard first, or nil if the heard list is not enabled.
+func (c *Client) HeardStations() []HeardStation {
+	if c.heard == nil {
+		return nil
+	}
+	return c.heard.list(c.clock.Now())
+}
+
+// add records a parsed packet received at now. Packets that did not parse far
+// enough to yield a source callsign are ignored. The position of an object or
+// item belongs to the object, so only the sender's own positions are kept.
+// Expired stations are left for list to drop.
+func (h *heardList) add(p *parser.Parsed, now time.Time) {
+	if p.From == "" {
+		return
+	}
+	call := aprsutils.CallsignKey(p.From)
+	own := p.HasPosition && !p.PacketType.Has(parser.TypeObject|parser.TypeItem)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, ok := h.stations[call]
+	if ok {
+		h.order.MoveToFront(e)
+	} else {
+		e = h.order.PushFront(&HeardStation{Callsign: call})
+		h.stations[call] = e
+	}
+	s := e.Value.(*HeardStation)
+	s.LastHeard = now
+	s.Packets++
+	if own {
+		s.HasPosition, s.Lat, s.Lon = true, p.Lat, p.Lon
+	}
+
+	for h.max > 0 && h.order.Len() > h.max {
+		h.remove(h.order.Back())
+	}
+}
+
+// list returns copies of the live entries at now, most recent first.
+func (h *heardList) list(now time.Time) []HeardStation {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.prune(now)
+	out := make([]HeardStation, 0, h.order.Len())
+	for e := h.order.Front(); e != nil; e = e.Next() {
+		out = append(out, *e.Value.(*HeardStation))
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].LastHeard.Equal(out[j].LastHeard) {
+			return out[i].LastHeard.After(out[j].LastHeard)
+		}
+		return out[i].Callsign < out[j].Callsign
+	})
+	return out
+}
+
+// prune drops the stations not heard within the TTL, oldest first; h.mu must
+// be held.
+func (h *heardList) prune(now time.Time) {
+	if h.ttl <= 0 {
+		return
+	}
+	for e := h.order.Back(); e != nil && now.Sub(e.Value.(*HeardStation).LastHeard) > h.ttl; e = h.order.Back() {
+		h.remove(e)
+	}
+}
+
+// remove drops the station held by e; h.mu must be held.
+func (h *heardList) remove(e *list.Element) {
+	h.order.Remove(e)
+	delete(h.stations, e.Value.(*HeardStation).Callsign)
+}
@@ -0,0 +1,69 @@
+package client
+
+import "sync"
+
+// packetsBuffer is the capacity of the channel returned by Packets.
+const packetsBuffer = 64
+
+// packetFeed is the channel behind Packets. mu is held for reading while a
+// packet is being delivered and for writing when the channel is created or
+// closed, so a close never races a send.
+type packetFeed struct {
+	mu     sync.RWMutex
+	ch     chan string
+	closed bool
+}
+
+// Packets returns a channel receiving every packet the client receives, as
+// an alternative to WithHandler for consumers that prefer to range or
+// select over packets. The channel holds packetsBuffer packets; once it is
+// full the receive loop blocks until the consumer catches up, which in turn
+// stops reading from the server (backpressure). It is closed when the client
+// is done (see Wait), so a range over it ends.
+//
+// Packets may be used together with WithHandler and WithReceivedHandler:
+// each packet is sent to the channel first and then passed to the
+// callbacks. Every call returns the same channel.
+func (c *Client) Packets() <-chan string {
+	f := &c.packets
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ch == nil {
+		f.ch = make(chan string, packetsBuffer)
+		if f.closed {
+			close(f.ch)
+		}
+	}
+	return f.ch
+}
+
+// deliver sends packet to the Packets channel, if one was requested. It
+// gives up when the client is done rather than block forever on a consumer
+// that has stopped reading.
+func (c *Client) deliver(packet string) {
+	f := &c.packets
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.ch == nil || f.closed {
+		return
+	}
+	select {
+	case f.ch <- packet:
+	case <-c.done:
+	}
+}
+
+// closePackets closes the Packets channel. It must run after c.done is
+// closed, which releases any deliver blocked on a full channel.
+func (c *Client) closePackets() {
+	f := &c.packets
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	f.closed = true
+	if f.ch != nil {
+		close(f.ch)
+	}
+}
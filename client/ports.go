@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Standard APRS-IS server ports.
+const (
+	PortFullfeed    = 10152 // full feed, no filter
+	PortFiltered    = 14580 // user-defined filter (igate)
+	PortFilteredTLS = 24580 // user-defined filter over TLS
+	PortUDPSubmit   = 8080  // UDP submit
+)
+
+// ErrPortModeMismatch is returned by ResolveServer when an explicit port is
+// the standard port of the other mode, e.g. a filtered client on 10152.
+var ErrPortModeMismatch = errors.New("port belongs to the other client mode")
+
+// DefaultPort returns the standard APRS-IS port for mode. There is no common
+// TLS port for the full feed, so DefaultPort(Fullfeed, true) returns 0.
+func DefaultPort(mode Mode, tls bool) int {
+	switch {
+	case mode == Fullfeed && !tls:
+		return PortFullfeed
+	case mode == Fullfeed:
+		return 0
+	case tls:
+		return PortFilteredTLS
+	default:
+		return PortFiltered
+	}
+}
+
+// ResolveServer splits a "host" or "host:port" server address, filling in
+// DefaultPort when the port is omitted. It rejects ports outside 1-65535
+// and a standard port of the other mode (ErrPortModeMismatch), the usual
+// cause of a filter silently doing nothing.
+func ResolveServer(address string, mode Mode, tls bool) (host string, port int, err error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		// No port given
+		host, port = address, DefaultPort(mode, tls)
+		if port == 0 {
+			return "", 0, errors.New("no default port for this mode")
+		}
+		return host, port, nil
+	}
+
+	port, err = strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, errors.New("invalid port")
+	}
+	if (mode == Fullfeed && port == PortFiltered) || (mode == IGate && port == PortFullfeed) {
+		return "", 0, ErrPortModeMismatch
+	}
+	return host, port, nil
+}
+
+// ServerAddr is one server of a DNS rotation pool such as rotate.aprs2.net.
+type ServerAddr struct {
+	Hostname string // the pool name it was resolved from
+	IP       net.IP
+}
+
+// Address returns the server's "ip:port" for dialling, bracketing IPv6.
+func (a ServerAddr) Address(port int) string {
+	return net.JoinHostPort(a.IP.String(), strconv.Itoa(port))
+}
+
+// lookupIPAddr resolves hostnames for ResolveRotate; tests replace it.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// ResolveRotate looks up every A and AAAA record of a rotation hostname, so
+// that a client can fail over across the whole pool itself instead of
+// reconnecting to whatever one address the resolver hands out next. Servers
+// are returned in the resolver's order, which keeps the load spread across
+// the pool, without duplicates. A failed lookup, or one without addresses,
+// is an error.
+func ResolveRotate(hostname string) ([]ServerAddr, error) {
+	addrs, err := lookupIPAddr(context.Background(), hostname)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", hostname, err)
+	}
+
+	servers := make([]ServerAddr, 0, len(addrs))
+	seen := make(map[string]bool, len(addrs))
+	for _, a := range addrs {
+		if key := a.IP.String(); !seen[key] {
+			seen[key] = true
+			servers = append(servers, ServerAddr{Hostname: hostname, IP: a.IP})
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("resolve %s: no addresses", hostname)
+	}
+	return servers, nil
+}
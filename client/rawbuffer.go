@@ -0,0 +1,58 @@
+package client
+
+import "sync"
+
+// rawRing is a fixed-size ring of the most recently received raw packets.
+// The receive loop adds to it and any goroutine may read it, so every access
+// holds mu; each holds it only to copy strings, never across I/O.
+type rawRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int  // index the next line is written to
+	full  bool // every slot has been written at least once
+}
+
+// WithRawBuffer makes the client keep the last n raw packets it received,
+// read with RecentRaw, e.g. to reproduce a packet a handler choked on. A
+// zero or negative n leaves it disabled.
+func WithRawBuffer(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.raw = &rawRing{lines: make([]string, n)}
+		}
+	}
+}
+
+// RecentRaw returns the last packets received, oldest first, as they came
+// off the wire without the line ending. It returns nil if WithRawBuffer is
+// not enabled.
+func (c *Client) RecentRaw() []string {
+	if c.raw == nil {
+		return nil
+	}
+	return c.raw.list()
+}
+
+// add records line, overwriting the oldest one once the ring is full.
+func (r *rawRing) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next++
+	if r.next == len(r.lines) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// list returns a copy of the ring's lines, oldest first.
+func (r *rawRing) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	out := make([]string, 0, len(r.lines))
+	out = append(out, r.lines[r.next:]...)
+	return append(out, r.lines[:r.next]...)
+}
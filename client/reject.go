@@ -0,0 +1,58 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrServerFull reports that the server turned the connection away
+	// because it has no free slots ("# Port full."). It is transient: the
+	// client keeps retrying, but each rejected connection uses up one of the
+	// WithRetryTimes attempts.
+	ErrServerFull = errors.New("server is full")
+	// ErrLoginRejected reports that the server refused the login (bad or
+	// disallowed callsign, malformed login line). It is permanent: the client
+	// stops reconnecting, since the same login would be refused again.
+	ErrLoginRejected = errors.New("server rejected the login")
+)
+
+// rejectionPhrases maps known "#" server messages, lower-cased, to the kind of
+// rejection they announce.
+var rejectionPhrases = []struct {
+	phrase string
+	err    error
+}{
+	{"port full", ErrServerFull},
+	{"server full", ErrServerFull},
+	{"server is full", ErrServerFull},
+	{"too many connections", ErrServerFull},
+	{"bad login", ErrLoginRejected},
+	{"invalid login", ErrLoginRejected},
+	{"login rejected", ErrLoginRejected},
+	{"login by user not allowed", ErrLoginRejected},
+	{"invalid callsign", ErrLoginRejected},
+}
+
+// rejection returns the rejection announced by a "#" server line, wrapping
+// ErrServerFull or ErrLoginRejected with the line itself, or nil if the line
+// is ordinary server info.
+func rejection(line string) error {
+	lower := strings.ToLower(line)
+	for _, r := range rejectionPhrases {
+		if strings.Contains(lower, r.phrase) {
+			return fmt.Errorf("%w: %s", r.err, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+		}
+	}
+	return nil
+}
+
+// Rejection returns the last rejection the server announced (see
+// ErrServerFull and ErrLoginRejected), or nil. It is cleared when a later
+// login succeeds. Test it with errors.Is.
+func (c *Client) Rejection() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rejected
+}
@@ -0,0 +1,46 @@
+package client
+
+// ConnectionState is a ENUM type for the client's connection state
+type ConnectionState string
+
+const (
+	Disconnected ConnectionState = "disconnected" // not connected (initial state)
+	Connecting   ConnectionState = "connecting"   // Connect is dialling the server
+	Connected    ConnectionState = "connected"    // link up
+	Reconnecting ConnectionState = "reconnecting" // link dropped, retrying on its own
+	Closed       ConnectionState = "closed"       // Close was called; final
+)
+
+// WithStateCallback sets a callback fired on every connection state change
+// with the old and new state. It runs on the goroutine making the change
+// (Connect, Close or the receive loop), so it should return quickly.
+func WithStateCallback(onState func(old, new ConnectionState)) Option {
+	return func(c *Client) {
+		c.onState = onState
+	}
+}
+
+// State returns the client's current connection state.
+func (c *Client) State() ConnectionState {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.state
+}
+
+// transition moves the client to state to and reports the change. Closed is
+// final, so nothing moves the client out of it. It must not be called with
+// c.mu held, since the callback may use the client.
+func (c *Client) transition(to ConnectionState) {
+	c.stateMu.Lock()
+	from := c.state
+	if from == to || from == Closed {
+		c.stateMu.Unlock()
+		return
+	}
+	c.state = to
+	c.stateMu.Unlock()
+
+	if c.onState != nil {
+		c.onState(from, to)
+	}
+}
@@ -1,6 +1,10 @@
 package aprsutils
 
-import "math"
+import (
+	"math"
+	"runtime"
+	"sync"
+)
 
 // CalculateDistanceVincentyInverse computes the distance between two coordinates using Vincenty Inverse formula
 func CalculateDistanceVincentyInverse(lat1, lon1, lat2, lon2 float64) float64 {
@@ -83,3 +87,92 @@ func CalculateDistanceHaversine(lat1, lon1, lat2, lon2 float64) float64 {
 func toRadians(angle float64) float64 {
 	return angle * math.Pi / 180
 }
+
+// LatLon is a position in decimal degrees.
+type LatLon struct {
+	Lat float64
+	Lon float64
+}
+
+// WithinRadius reports whether p lies within radiusKm kilometres of center,
+// measured with CalculateDistanceHaversine.
+func WithinRadius(center, p LatLon, radiusKm float64) bool {
+	return CalculateDistanceHaversine(center.Lat, center.Lon, p.Lat, p.Lon) <= radiusKm
+}
+
+// DistanceOption configures DistanceMatrix.
+type DistanceOption func(*distanceConfig)
+
+// distanceConfig provides DistanceMatrix options
+type distanceConfig struct {
+	vincenty bool
+}
+
+// WithVincenty makes DistanceMatrix use CalculateDistanceVincentyInverse
+// (WGS-84, slower) instead of CalculateDistanceHaversine.
+func WithVincenty() DistanceOption {
+	return func(c *distanceConfig) {
+		c.vincenty = true
+	}
+}
+
+// parallelMatrixSize is the point count from which DistanceMatrix spreads the
+// rows over GOMAXPROCS goroutines.
+const parallelMatrixSize = 256
+
+// DistanceMatrix returns the pairwise distances, in kilometres, between
+// points: m[i][j] is the distance from points[i] to points[j]. The matrix is
+// symmetric with a zero diagonal; each pair is computed once. Haversine is
+// used unless WithVincenty is given, in which case near-antipodal pairs may be
+// NaN (see CalculateDistanceVincentyInverse). Inputs of parallelMatrixSize
+// points or more are computed concurrently.
+func DistanceMatrix(points []LatLon, options ...DistanceOption) [][]float64 {
+	conf := &distanceConfig{}
+	for _, opt := range options {
+		opt(conf)
+	}
+	distance := CalculateDistanceHaversine
+	if conf.vincenty {
+		distance = CalculateDistanceVincentyInverse
+	}
+
+	n := len(points)
+	backing := make([]float64, n*n)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = backing[i*n : (i+1)*n]
+	}
+
+	// Row i fills m[i][j] and m[j][i] for j > i only, so rows can be handed
+	// to different goroutines without overlapping writes.
+	row := func(i int) {
+		for j := i + 1; j < n; j++ {
+			d := distance(points[i].Lat, points[i].Lon, points[j].Lat, points[j].Lon)
+			m[i][j] = d
+			m[j][i] = d
+		}
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if n < parallelMatrixSize || workers < 2 {
+		for i := 0; i < n; i++ {
+			row(i)
+		}
+		return m
+	}
+
+	// Rows shrink as i grows; striding them across workers balances the load.
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(start int) {
+			defer wg.Done()
+			for i := start; i < n; i += workers {
+				row(i)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	return m
+}
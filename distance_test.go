@@ -0,0 +1,65 @@
+package aprsutils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceMatrix(t *testing.T) {
+	points := []LatLon{
+		{0, 0},
+		{0, 1},
+		{49.0583, -72.0292},
+		{60.4752, 25.0947},
+	}
+
+	m := DistanceMatrix(points)
+	if !approxKm(m[0][1], 111.19, 0.01) {
+		t.Errorf("1 degree along the equator = %v km, want ~111.19", m[0][1])
+	}
+	for i := range points {
+		if m[i][i] != 0 {
+			t.Errorf("m[%d][%d] = %v, want 0", i, i, m[i][i])
+		}
+		for j := range points {
+			if m[i][j] != m[j][i] {
+				t.Errorf("m[%d][%d] = %v != m[%d][%d] = %v", i, j, m[i][j], j, i, m[j][i])
+			}
+			if i != j {
+				want := CalculateDistanceHaversine(points[i].Lat, points[i].Lon, points[j].Lat, points[j].Lon)
+				if m[i][j] != want {
+					t.Errorf("m[%d][%d] = %v, want %v", i, j, m[i][j], want)
+				}
+			}
+		}
+	}
+
+	v := DistanceMatrix(points, WithVincenty())
+	if !approxKm(v[0][1], 111.32, 0.01) {
+		t.Errorf("Vincenty 1 degree along the equator = %v km, want ~111.32", v[0][1])
+	}
+}
+
+// TestDistanceMatrixParallel checks the concurrent path matches direct calls.
+func TestDistanceMatrixParallel(t *testing.T) {
+	points := make([]LatLon, parallelMatrixSize+17)
+	for i := range points {
+		points[i] = LatLon{Lat: float64(i%170) - 85, Lon: float64(i*7%360) - 180}
+	}
+	m := DistanceMatrix(points)
+	for i := range points {
+		for j := range points {
+			want := 0.0
+			if i != j {
+				want = CalculateDistanceHaversine(points[i].Lat, points[i].Lon, points[j].Lat, points[j].Lon)
+			}
+			if m[i][j] != want {
+				t.Fatalf("m[%d][%d] = %v, want %v", i, j, m[i][j], want)
+			}
+		}
+	}
+}
+
+func approxKm(got, want, tol float64) bool {
+	return math.Abs(got-want) <= tol
+}
@@ -18,6 +18,8 @@
 package filter
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/APRSCN/aprsutils/parser"
@@ -83,6 +85,60 @@ func Compile(s string) *Filter {
 	return f
 }
 
+// ErrInvalidFilter is wrapped by the errors Validate returns.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// maxArgs is the largest argument count each filter type accepts; Validate
+// rejects extra arguments, which Compile silently ignores.
+var maxArgs = map[byte]int{
+	'a': 4, 'f': 2, 'm': 1, 'q': 2, 'r': 3, 's': 3, 't': 3,
+}
+
+// Validate checks a filter string the way Compile parses it, but reports the
+// first spec Compile would drop instead of skipping it. It is stricter than
+// Compile: it also rejects extra arguments, latitudes/longitudes out of range,
+// unknown t/ type letters and a malformed t/ range. An empty string is valid.
+func Validate(s string) error {
+	for _, tok := range strings.Fields(s) {
+		sp, ok := compileSpec(tok)
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidFilter, tok)
+		}
+		if reason := checkSpec(&sp); reason != "" {
+			return fmt.Errorf("%w: %q: %s", ErrInvalidFilter, tok, reason)
+		}
+	}
+	return nil
+}
+
+// checkSpec applies Validate's extra checks to a compiled spec and returns
+// why it is rejected, or "" when it is fine.
+func checkSpec(sp *spec) string {
+	if n, ok := maxArgs[sp.typ]; ok && len(sp.args) > n {
+		return fmt.Sprintf("too many arguments (max %d)", n)
+	}
+	switch d := sp.compiled.(type) {
+	case *rangeData:
+		if sp.typ == 'r' && (d.lat < -90 || d.lat > 90 || d.lon < -180 || d.lon > 180) {
+			return "coordinates out of range"
+		}
+	case *areaData:
+		if d.latN > 90 || d.latS < -90 || d.lonW < -180 || d.lonE > 180 {
+			return "coordinates out of range"
+		}
+	case *typeData:
+		for _, c := range sp.args[0] {
+			if !strings.ContainsRune("poimqstuwnc*", c) {
+				return fmt.Sprintf("unknown type %q", c)
+			}
+		}
+		if len(sp.args) == 2 || (len(sp.args) == 3 && !d.hasRange) {
+			return "type range needs call/km"
+		}
+	}
+	return ""
+}
+
 // compileSpec compiles a single token such as "r/60/25/100" or "-t/m".
 func compileSpec(tok string) (spec, bool) {
 	sp := spec{raw: tok}
@@ -1,6 +1,7 @@
 package filter
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/APRSCN/aprsutils/parser"
@@ -301,3 +302,40 @@ func TestEmptyAndNegationOnly(t *testing.T) {
 		t.Error("negation-only filter should match nothing")
 	}
 }
+
+func TestValidate(t *testing.T) {
+	valid := []string{
+		"",
+		"r/33/-96/100 t/m",
+		"-t/m p/N0 b/N0CALL*",
+		"a/60/20/50/30",
+		"t/poimqstuwnc*/N0CALL/50",
+		"f/N0CALL/25 m/10",
+		"s/>/#/R q/C/I os/OBJ",
+	}
+	for _, s := range valid {
+		if err := Validate(s); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", s, err)
+		}
+	}
+
+	invalid := []string{
+		"r/33/-96",           // missing distance
+		"r/33/-96/-1",        // negative distance
+		"r/95/0/10",          // latitude out of range
+		"r/33/-96/100/5",     // extra argument
+		"a/50/20/60/30",      // north below south
+		"a/60/-190/50/30",    // longitude out of range
+		"t/x",                // unknown type letter
+		"t/m/N0CALL",         // range without distance
+		"t/m/N0CALL/far",     // non-numeric distance
+		"z/foo",              // unknown filter type
+		"b",                  // no arguments
+		"p/N0 r/abc/-96/100", // one bad spec fails the whole string
+	}
+	for _, s := range invalid {
+		if err := Validate(s); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Validate(%q) = %v, want ErrInvalidFilter", s, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package kiss encodes and decodes KISS TNC frames (the serial framing
+// carrying AX.25 frames between a host and a TNC), including the SMACK
+// variant that appends a CRC-16 to each data frame.
+package kiss
+
+import "errors"
+
+// KISS special bytes.
+const (
+	FEND  = 0xC0 // frame end
+	FESC  = 0xDB // frame escape
+	TFEND = 0xDC // transposed FEND (after FESC)
+	TFESC = 0xDD // transposed FESC (after FESC)
+)
+
+// CmdData is the KISS command for a data frame.
+const CmdData = 0x00
+
+// smackFlag is the bit of the type byte that marks a SMACK frame, whose
+// data is followed by a CRC-16. SMACK limits ports to 0-7 to make room for it.
+const smackFlag = 0x80
+
+// Decoding errors.
+var (
+	ErrEmptyFrame     = errors.New("kiss frame is empty")
+	ErrBadEscape      = errors.New("kiss frame has an invalid escape sequence")
+	ErrShortFrame     = errors.New("smack frame is too short for its checksum")
+	ErrSMACKChecksum  = errors.New("smack frame checksum mismatch")
+	ErrPortOutOfRange = errors.New("kiss port out of range")
+)
+
+// Option configures Decode.
+type Option func(*config)
+
+type config struct {
+	smack bool
+}
+
+// WithSMACK makes Decode treat a data frame whose type byte has the high bit
+// set as SMACK: its CRC is verified and stripped. Use it for a TNC that
+// speaks SMACK. Without it the high bit is part of the port number (ports
+// 8-15), as in plain KISS.
+func WithSMACK() Option {
+	return func(c *config) {
+		c.smack = true
+	}
+}
+
+// Frame is a decoded KISS frame.
+type Frame struct {
+	Port    int    // TNC port, 0-15 (0-7 for SMACK)
+	Command byte   // low nibble of the type byte, CmdData for packets
+	Data    []byte // frame payload, e.g. an AX.25 frame; CRC removed
+	SMACK   bool   // frame carried (and passed) a SMACK CRC
+}
+
+// Decode decodes one KISS frame. Leading and trailing FEND bytes are
+// optional. With WithSMACK, a SMACK frame (type byte high bit set) has its
+// CRC verified and stripped, so plain and SMACK TNCs yield the same
+// Frame.Data.
+func Decode(frame []byte, options ...Option) (Frame, error) {
+	var conf config
+	for _, opt := range options {
+		opt(&conf)
+	}
+
+	for len(frame) > 0 && frame[0] == FEND {
+		frame = frame[1:]
+	}
+	for len(frame) > 0 && frame[len(frame)-1] == FEND {
+		frame = frame[:len(frame)-1]
+	}
+	if len(frame) == 0 {
+		return Frame{}, ErrEmptyFrame
+	}
+
+	raw, err := unescape(frame)
+	if err != nil {
+		return Frame{}, err
+	}
+
+	typ := raw[0]
+	f := Frame{Port: int(typ >> 4), Command: typ & 0x0f}
+
+	if conf.smack && typ&smackFlag != 0 && f.Command == CmdData {
+		// The CRC covers the type byte and data; appending it makes the
+		// CRC of the whole frame zero.
+		if len(raw) < 3 {
+			return Frame{}, ErrShortFrame
+		}
+		if crc16(raw) != 0 {
+			return Frame{}, ErrSMACKChecksum
+		}
+		f.Port &= 0x07
+		f.SMACK = true
+		raw = raw[:len(raw)-2]
+	}
+
+	f.Data = raw[1:]
+	return f, nil
+}
+
+// Encode builds a KISS frame, including the surrounding FEND bytes. With
+// f.SMACK set the frame is flagged and a CRC is appended, and f.Port must be
+// 0-7.
+func Encode(f Frame) ([]byte, error) {
+	maxPort := 15
+	if f.SMACK {
+		maxPort = 7
+	}
+	if f.Port < 0 || f.Port > maxPort {
+		return nil, ErrPortOutOfRange
+	}
+
+	raw := make([]byte, 0, len(f.Data)+3)
+	typ := byte(f.Port)<<4 | f.Command&0x0f
+	if f.SMACK {
+		typ |= smackFlag
+	}
+	raw = append(raw, typ)
+	raw = append(raw, f.Data...)
+	if f.SMACK {
+		crc := crc16(raw)
+		raw = append(raw, byte(crc), byte(crc>>8)) // low byte first
+	}
+
+	out := make([]byte, 0, len(raw)+4)
+	out = append(out, FEND)
+	for _, b := range raw {
+		switch b {
+		case FEND:
+			out = append(out, FESC, TFEND)
+		case FESC:
+			out = append(out, FESC, TFESC)
+		default:
+			out = append(out, b)
+		}
+	}
+	return append(out, FEND), nil
+}
+
+// unescape reverses KISS byte stuffing.
+func unescape(frame []byte) ([]byte, error) {
+	out := make([]byte, 0, len(frame))
+	for i := 0; i < len(frame); i++ {
+		b := frame[i]
+		if b != FESC {
+			out = append(out, b)
+			continue
+		}
+		i++
+		if i == len(frame) {
+			return nil, ErrBadEscape
+		}
+		switch frame[i] {
+		case TFEND:
+			out = append(out, FEND)
+		case TFESC:
+			out = append(out, FESC)
+		default:
+			return nil, ErrBadEscape
+		}
+	}
+	return out, nil
+}
+
+// crc16 is the SMACK checksum: CRC-16 with polynomial 0x8005 (reflected,
+// 0xA001), initial value 0.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
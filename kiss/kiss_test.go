@@ -0,0 +1,69 @@
+package kiss
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCRC16(t *testing.T) {
+	// CRC-16/ARC check value.
+	if got := crc16([]byte("123456789")); got != 0xBB3D {
+		t.Errorf("crc16 = %#04x, want 0xbb3d", got)
+	}
+}
+
+func TestDecodePlain(t *testing.T) {
+	frame := []byte{FEND, 0x10, 'A', FESC, TFEND, 'B', FESC, TFESC, FEND}
+	f, err := Decode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Port != 1 || f.Command != CmdData || f.SMACK || !bytes.Equal(f.Data, []byte{'A', FEND, 'B', FESC}) {
+		t.Errorf("Decode = %+v", f)
+	}
+
+	enc, err := Encode(Frame{Port: 1, Data: []byte{'A', FEND, 'B', FESC}})
+	if err != nil || !bytes.Equal(enc, frame) {
+		t.Errorf("Encode = %x, %v; want %x", enc, err, frame)
+	}
+
+	// Ports 8-15 set the high bit; without WithSMACK that is still plain KISS.
+	enc, err = Encode(Frame{Port: 9, Data: []byte("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, err := Decode(enc); err != nil || f.Port != 9 || f.SMACK || string(f.Data) != "x" {
+		t.Errorf("port 9: Decode = %+v, %v", f, err)
+	}
+}
+
+func TestDecodeSMACK(t *testing.T) {
+	data := []byte("N0CALL>APRS:>smack")
+	enc, err := Encode(Frame{Port: 2, Data: data, SMACK: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc[1] != 0xA0 {
+		t.Errorf("type byte = %#x, want 0xa0", enc[1])
+	}
+
+	f, err := Decode(enc, WithSMACK())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Port != 2 || !f.SMACK || !bytes.Equal(f.Data, data) {
+		t.Errorf("Decode = %+v", f)
+	}
+
+	// Flip a payload bit: the checksum must catch it.
+	bad := append([]byte(nil), enc...)
+	bad[5] ^= 0x01
+	if _, err := Decode(bad, WithSMACK()); !errors.Is(err, ErrSMACKChecksum) {
+		t.Errorf("corrupted frame: err = %v, want ErrSMACKChecksum", err)
+	}
+
+	if _, err := Encode(Frame{Port: 8, SMACK: true}); !errors.Is(err, ErrPortOutOfRange) {
+		t.Errorf("SMACK port 8: err = %v, want ErrPortOutOfRange", err)
+	}
+}
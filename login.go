@@ -0,0 +1,49 @@
+package aprsutils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// BuildLoginString returns the APRS-IS login line
+//
+//	user CALL pass PASSCODE vers SOFTWARE VERSION filter FILTER
+//
+// without the trailing CRLF. The pass and filter parts are left out when
+// passcode or filter is empty. The server splits the line on spaces, so
+// whitespace inside software or version is replaced with '-' (e.g. "My App"
+// becomes "My-App"), and control characters such as CR and LF are dropped from
+// every field so they cannot end the line early.
+func BuildLoginString(callsign, passcode, software, version, filter string) string {
+	var b strings.Builder
+	b.WriteString("user ")
+	b.WriteString(loginField(callsign, ""))
+	if passcode = loginField(passcode, ""); passcode != "" {
+		b.WriteString(" pass ")
+		b.WriteString(passcode)
+	}
+	b.WriteString(" vers ")
+	b.WriteString(loginField(software, "-"))
+	b.WriteString(" ")
+	b.WriteString(loginField(version, "-"))
+	if filter = loginField(filter, " "); filter != "" {
+		b.WriteString(" filter ")
+		b.WriteString(filter)
+	}
+	return b.String()
+}
+
+// loginField trims s, drops control characters and replaces each run of
+// whitespace with sep (dropping it when sep is empty).
+func loginField(s, sep string) string {
+	fields := strings.FieldsFunc(s, unicode.IsSpace)
+	for i, f := range fields {
+		fields[i] = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, f)
+	}
+	return strings.Join(fields, sep)
+}
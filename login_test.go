@@ -0,0 +1,27 @@
+package aprsutils
+
+import "testing"
+
+func TestBuildLoginString(t *testing.T) {
+	cases := []struct {
+		callsign, passcode, software, version, filter string
+		want                                          string
+	}{
+		{"N0CALL", "13023", "aprsutils", "1.0", "r/33/-97/200",
+			"user N0CALL pass 13023 vers aprsutils 1.0 filter r/33/-97/200"},
+		{"N0CALL-10", "-1", "aprsutils", "1.0", "",
+			"user N0CALL-10 pass -1 vers aprsutils 1.0"},
+		{"N0CALL", "", "aprsutils", "1.0", "",
+			"user N0CALL vers aprsutils 1.0"},
+		{"N0CALL", "13023", "My APRS App", "1.0 beta", "b/N0CALL*  t/m",
+			"user N0CALL pass 13023 vers My-APRS-App 1.0-beta filter b/N0CALL* t/m"},
+		{"N0CALL\r\n", "13023", "app\n", "1.0", "m/50\r\n",
+			"user N0CALL pass 13023 vers app 1.0 filter m/50"},
+	}
+	for _, c := range cases {
+		if got := BuildLoginString(c.callsign, c.passcode, c.software, c.version, c.filter); got != c.want {
+			t.Errorf("BuildLoginString(%q, %q, %q, %q, %q) = %q, want %q",
+				c.callsign, c.passcode, c.software, c.version, c.filter, got, c.want)
+		}
+	}
+}
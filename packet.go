@@ -0,0 +1,37 @@
+package aprsutils
+
+import "strings"
+
+// DefaultToCall is the destination used for packets built by this library.
+const DefaultToCall = "APRS"
+
+// buildPacket assembles a TNC2-format packet "from>to[,path...]:info".
+func buildPacket(from, to string, path []string, info string) string {
+	var b strings.Builder
+	b.WriteString(from)
+	b.WriteString(">")
+	b.WriteString(to)
+	for _, hop := range path {
+		b.WriteString(",")
+		b.WriteString(hop)
+	}
+	b.WriteString(":")
+	b.WriteString(info)
+	return b.String()
+}
+
+// messageInfo builds a message information field ":ADDRESSEE:text", padding
+// the addressee to the fixed nine characters.
+func messageInfo(addressee, text string) string {
+	return ":" + padAddressee(addressee) + ":" + text
+}
+
+// padAddressee upper-cases and space-pads (or truncates) a message addressee
+// to the nine characters the message format requires.
+func padAddressee(addressee string) string {
+	addressee = strings.ToUpper(strings.TrimSpace(addressee))
+	if len(addressee) > 9 {
+		return addressee[:9]
+	}
+	return addressee + strings.Repeat(" ", 9-len(addressee))
+}
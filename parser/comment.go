@@ -4,22 +4,17 @@ import (
 	"math"
 	"strconv"
 	"strings"
-
-	"go.gh.ink/regexp"
+	"time"
 
 	"github.com/APRSCN/aprsutils"
 	"github.com/APRSCN/aprsutils/utils"
 )
 
-// parseComment parses comment from APRS packet
+// parseComment parses comment from APRS packet. Each extension parser below
+// checks a cheap byte-level marker ("/A=", '|', '!', "PHG", ...) before running
+// its regexp, so the common short position comment skips them all.
 func (p *Parsed) parseComment(body string) string {
-	body = p.parseDataExtensions(body)
-
-	body = p.parseCommentAltitude(body)
-
-	body = p.parseCommentTelemetry(body)
-
-	body = p.parseDAO(body)
+	body = p.runCommentSteps(body, commentSteps)
 
 	if utils.StringLen(body) > 0 && string([]rune(body)[0]) == "/" {
 		body = string([]rune(body)[1:])
@@ -29,38 +24,74 @@ func (p *Parsed) parseComment(body string) string {
 	return body
 }
 
+// commentSteps are the comment extension decoders, in order. Mic-E carries
+// course and speed in its body, so it runs commentSteps[1:].
+var commentSteps = []func(*Parsed, string) string{
+	(*Parsed).parseDataExtensions,
+	(*Parsed).parseCommentAltitude,
+	(*Parsed).parseCommentTelemetry,
+	(*Parsed).parseDAO,
+	(*Parsed).parseSignalReport,
+}
+
+// runCommentSteps applies steps to body in turn. It stops early once the
+// parse Timeout has run out, which Parse then reports as ErrParseTimeout.
+func (p *Parsed) runCommentSteps(body string, steps []func(*Parsed, string) string) string {
+	for _, step := range steps {
+		if p.limits.expired() {
+			break
+		}
+		body = step(p, body)
+	}
+	return body
+}
+
 // parseDataExtensions parses data extensions from APRS packet
 func (p *Parsed) parseDataExtensions(body string) string {
 	// Course speed bearing nrq
 	// Page 27 of the spec
 	// Format: 111/222/333/444text
-	pattern1 := `^([0-9 \.]{3})/([0-9 \.]{3})`
-	re1 := regexp.MustCompile(pattern1)
-	matches := re1.FindStringSubmatch(body)
+	var matches []string
+	if len(body) >= 7 && body[3] == '/' {
+		pattern1 := `^([0-9 \.]{3})/([0-9 \.]{3})`
+		re1 := aprsutils.CompiledRegexps.MustCompile(pattern1)
+		matches = re1.FindStringSubmatch(body)
+	}
 
 	if len(matches) >= 3 {
 		cse, spd := matches[1], matches[2]
 		body = string([]rune(body)[7:])
 
+		p.RawCourseSpeed = cse + "/" + spd
+		p.IsFixed = cse == "000" && spd == "000"
+
 		if utils.IsDigit(cse) && cse != "000" {
 			cseInt, _ := strconv.Atoi(cse)
 			if cseInt >= 1 && cseInt <= 360 {
 				p.Course = float64(cseInt)
 			} else {
+				p.warn("course", "course "+cse+" out of range 1-360")
 				p.Course = 0
 			}
+		} else if strings.Trim(cse, " .") != "" {
+			p.warn("course", "course "+cse+" unreadable, ignored")
 		}
 
 		if utils.IsDigit(spd) && spd != "000" {
 			spdInt, _ := strconv.Atoi(spd)
 			p.Speed = float64(spdInt) * 1.852
+		} else if !utils.IsDigit(spd) && strings.Trim(spd, " .") != "" {
+			p.warn("speed", "speed "+spd+" unreadable, ignored")
 		}
 
 		// DF Report format
 		// Page 29 of teh spec
-		pattern2 := `^/([0-9 \.]{3})/([0-9 \.]{3})`
-		re2 := regexp.MustCompile(pattern2)
-		matches2 := re2.FindStringSubmatch(body)
+		var matches2 []string
+		if len(body) >= 8 && body[0] == '/' {
+			pattern2 := `^/([0-9 \.]{3})/([0-9 \.]{3})`
+			re2 := aprsutils.CompiledRegexps.MustCompile(pattern2)
+			matches2 = re2.FindStringSubmatch(body)
+		}
 
 		if len(matches2) >= 3 {
 			// cse=000 means stations is fixed, Page 29 of the spec
@@ -81,11 +112,12 @@ func (p *Parsed) parseDataExtensions(body string) string {
 				p.NRQ = nrqInt
 			}
 		}
-	} else {
+	} else if strings.HasPrefix(body, "PHG") {
 		// PHG format: PHGabcd....
-		// RHGR format: RHGabcdr/....
+		// PHGR format: PHGabcdr/.... where r is the beacon rate, in
+		// transmissions per hour, as a base-36 digit (1-9, then A=10 to Z=35).
 		pattern3 := `^(PHG(\d[\x30-\x7e]\d\d)([0-9A-Z]\/)?)`
-		re3 := regexp.MustCompile(pattern3)
+		re3 := aprsutils.CompiledRegexps.MustCompile(pattern3)
 		matches3 := re3.FindStringSubmatch(body)
 
 		if len(matches3) >= 4 {
@@ -112,6 +144,7 @@ func (p *Parsed) parseDataExtensions(body string) string {
 				direction = "omni"
 			} else if phgDir == 9 {
 				direction = "invalid"
+				p.warn("phg", "PHG directivity 9 is invalid")
 			} else {
 				direction = strconv.Itoa(45 * phgDir)
 			}
@@ -124,45 +157,72 @@ func (p *Parsed) parseDataExtensions(body string) string {
 
 			if phgr != "" {
 				p.PHG = strings.Join([]string{phg, string([]rune(phgr)[0])}, "")
-				rate, _ := strconv.ParseInt(string([]rune(phgr)[0]), 16, 64)
+				rate, _ := strconv.ParseInt(string([]rune(phgr)[0]), 36, 64)
 				p.PHGRate = int(rate)
-			}
-		} else {
-			pattern4 := `^RNG(\d{4})`
-			re4 := regexp.MustCompile(pattern4)
-			matches4 := re4.FindStringSubmatch(body)
-
-			if len(matches4) >= 2 {
-				rng := matches4[1]
-				body = string([]rune(body)[7:])
-				rngInt, _ := strconv.Atoi(rng)
-				p.RNG = float64(rngInt) * 1.609344
+				if rate > 0 {
+					p.PHGInterval = time.Hour / time.Duration(rate)
+				}
 			}
 		}
+	} else if strings.HasPrefix(body, "RNG") {
+		pattern4 := `^RNG(\d{4})`
+		re4 := aprsutils.CompiledRegexps.MustCompile(pattern4)
+		matches4 := re4.FindStringSubmatch(body)
+
+		if len(matches4) >= 2 {
+			rng := matches4[1]
+			body = string([]rune(body)[7:])
+			rngInt, _ := strconv.Atoi(rng)
+			p.RNG = float64(rngInt) * 1.609344
+		}
 	}
 
 	return body
 }
 
-// parseCommentAltitude parses comment altitude from APRS packet
+// parseCommentAltitude parses comment altitude from APRS packet. The
+// "/A=" field is always removed from the comment but only fills Altitude
+// when the position format supplied none (see AltitudeSource).
 func (p *Parsed) parseCommentAltitude(body string) string {
+	if !strings.Contains(body, "/A=") {
+		return body
+	}
+
 	pattern := `^(.*?)/A=(\-\d{5}|\d{6})(.*)$`
-	re := regexp.MustCompile(pattern)
+	re := aprsutils.CompiledRegexps.MustCompile(pattern)
 	matches := re.FindStringSubmatch(body)
 
 	if len(matches) >= 4 {
 		body = matches[1] + matches[3]
-		altitude, _ := strconv.Atoi(matches[2])
-		p.Altitude = float64(altitude) * 0.3048
+		if p.AltitudeSource == AltitudeNone {
+			altitude, _ := strconv.Atoi(matches[2])
+			p.Altitude = float64(altitude) * 0.3048 // feet to metres
+			p.AltitudeSource = AltitudeComment
+		}
 	}
 
 	return body
 }
 
-// parseDAO parses DAO from APRS packet
+// DAODatums names the datums selected by the upper-cased "!DAO!" datum byte
+// (http://www.aprs.org/aprs12/datum.txt). The spec only assigns W (WGS84);
+// the map is exported so applications can add local conventions.
+var DAODatums = map[string]string{
+	"W": "WGS84",
+}
+
+// parseDAO parses the "!DAO!" precision extension. An upper-case datum byte
+// is followed by two digits adding thousandths of a minute to the latitude
+// and longitude; a lower-case one by two base-91 bytes adding v/91 hundredths
+// of a minute. Either refines the position away from zero (towards the
+// station's hemisphere).
 func (p *Parsed) parseDAO(body string) string {
+	if strings.Count(body, "!") < 2 {
+		return body
+	}
+
 	pattern := `^(.*)\!([\x21-\x7b])([\x20-\x7b]{2})\!(.*?)$`
-	re := regexp.MustCompile(pattern)
+	re := aprsutils.CompiledRegexps.MustCompile(pattern)
 	matches := re.FindStringSubmatch(body)
 
 	if len(matches) >= 5 {
@@ -170,18 +230,23 @@ func (p *Parsed) parseDAO(body string) string {
 		body += rest
 
 		p.DAODatumByte = strings.ToUpper(daobyte)
+		p.DAODatum = DAODatums[p.DAODatumByte]
 		latOffset, lonOffset := 0.0, 0.0
 
-		if daobyte == "W" && utils.IsDigit(dao) {
+		// The case of the datum byte, not the datum itself, selects the
+		// encoding.
+		if daobyte[0] >= 'A' && daobyte[0] <= 'Z' && utils.IsDigit(dao) {
 			dao0, _ := strconv.Atoi(string([]rune(dao)[0]))
 			dao1, _ := strconv.Atoi(string([]rune(dao)[1]))
 			latOffset = float64(dao0) * 0.001 / 60
 			lonOffset = float64(dao1) * 0.001 / 60
-		} else if daobyte == "w" && !strings.Contains(dao, " ") {
+		} else if daobyte[0] >= 'a' && daobyte[0] <= 'z' && aprsutils.IsBase91(dao) {
 			latBase91, _ := aprsutils.ToDecimal(string([]rune(dao)[0]))
 			lonBase91, _ := aprsutils.ToDecimal(string([]rune(dao)[1]))
 			latOffset = (float64(latBase91) / 91.0) * 0.01 / 60
 			lonOffset = (float64(lonBase91) / 91.0) * 0.01 / 60
+		} else if strings.TrimSpace(dao) != "" {
+			p.warn("dao", "DAO offsets "+dao+" unreadable, ignored")
 		}
 
 		if p.Lat >= 0 {
@@ -195,7 +260,61 @@ func (p *Parsed) parseDAO(body string) string {
 		} else {
 			p.Lon = p.Lon - lonOffset
 		}
+
+		p.Lat = normalizeLat(p.Lat)
+		p.Lon = normalizeLon(p.Lon)
 	}
 
 	return body
 }
+
+// SignalReport is a received signal strength reported at the end of a
+// comment, as used by RF coverage mapping tools.
+type SignalReport struct {
+	Raw   string  // matched text, e.g. "-87dBm" or "S9+20"
+	DBm   float64 // level in dBm; set only for dBm reports
+	SUnit int     // S-meter reading 1-9; 0 for dBm reports
+	Over  int     // dB over S9, e.g. 20 for "S9+20"
+}
+
+// parseSignalReport extracts a trailing signal report from the comment into
+// Parsed.SignalReport. These forms are recognised, each as the last word(s)
+// of the comment:
+//
+//   - an RSSI in dBm: "-87dBm", "-87.5 dBm" (-150 to 0 dBm)
+//   - S9 and the dB over it: "S9+20dB"
+//   - an S-meter reading after an "RSSI", "sig" or "signal" keyword:
+//     "RSSI S7", "sig S9+20", "signal: S5"
+//
+// A bare "S7" is not a report, since model and road names end that way
+// ("Galaxy S9", "Route S5"). Anything else, including these forms elsewhere
+// in the comment, is left alone.
+func (p *Parsed) parseSignalReport(body string) string {
+	// The report must be a word of its own: "-87dBm", not "x-87dBm".
+	re := aprsutils.CompiledRegexps.MustCompile(`^(?:(.*?)\s+)?((-\d{1,3}(?:\.\d+)?) ?dBm|S(9)\+(\d{1,2})dB|(?i:rssi|sig|signal):? ?S([1-9])(?:\+(\d{1,2})(?:dB)?)?)$`)
+	matches := re.FindStringSubmatch(strings.TrimRight(body, " "))
+	if matches == nil {
+		return body
+	}
+
+	report := &SignalReport{Raw: matches[2]}
+	sunit, over := matches[4]+matches[6], matches[5]+matches[7]
+	if matches[3] != "" {
+		dbm, _ := strconv.ParseFloat(matches[3], 64)
+		if dbm < -150 {
+			return body
+		}
+		report.DBm = dbm
+	} else {
+		report.SUnit, _ = strconv.Atoi(sunit)
+		if over != "" {
+			if report.SUnit != 9 {
+				return body
+			}
+			report.Over, _ = strconv.Atoi(over)
+		}
+	}
+
+	p.SignalReport = report
+	return matches[1]
+}
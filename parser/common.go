@@ -3,8 +3,7 @@ package parser
 import (
 	"errors"
 	"strings"
-
-	"go.gh.ink/regexp"
+	"unicode/utf8"
 
 	"github.com/APRSCN/aprsutils"
 	"github.com/APRSCN/aprsutils/utils"
@@ -20,7 +19,7 @@ func (p *Parsed) parseHeader(head string, conf *config) error {
 
 	// Check fromCall
 	if !(1 <= utils.StringLen(fromCall) && utils.StringLen(fromCall) <= 9) ||
-		!regexp.MustCompile(`(?i)^[a-z0-9]{0,9}(-[a-z0-9]{1,8})?$`).MatchString(fromCall) {
+		!aprsutils.CompiledRegexps.MustCompile(`(?i)^[a-z0-9]{0,9}(-[a-z0-9]{1,8})?$`).MatchString(fromCall) {
 		return errors.New("fromCallsign is invalid")
 	}
 
@@ -37,6 +36,7 @@ func (p *Parsed) parseHeader(head string, conf *config) error {
 
 	toCall := paths[0]
 	paths = paths[1:]
+	_, rawPath, _ := strings.Cut(path, ",")
 
 	// Validate callsign
 	if !conf.disableToCallsignValidate {
@@ -57,7 +57,7 @@ func (p *Parsed) parseHeader(head string, conf *config) error {
 
 	// Check callsign in paths
 	for _, pa := range paths {
-		if !regexp.MustCompile(`(?i)^[A-Z0-9\-]{1,9}\*?$`).MatchString(pa) {
+		if !aprsutils.CompiledRegexps.MustCompile(`(?i)^[A-Z0-9\-]{1,9}\*?$`).MatchString(pa) {
 			return errors.New("invalid callsign in path")
 		}
 	}
@@ -66,12 +66,47 @@ func (p *Parsed) parseHeader(head string, conf *config) error {
 	p.From = fromCall
 	p.To = toCall
 	p.Path = paths
+	p.RawPath = rawPath
 
 	return nil
 }
 
+// dataTypeFormats names the packet type of each data type character, as used
+// by WithEnabledFormats.
+var dataTypeFormats = map[string]string{
+	"!": "position", "=": "position", "/": "position", "@": "position",
+	"`": "mic-e", "‘": "mic-e", "'": "mic-e",
+	";": "object",
+	")": "item",
+	":": "message",
+	">": "status",
+	"T": "telemetry",
+	"_": "weather", "#": "weather", "*": "weather",
+	"}": "thirdparty",
+	"{": "user-defined",
+	"?": "query",
+	"$": "nmea",
+}
+
+// bodyFormat returns the WithEnabledFormats type name of a packet body, or ""
+// for bodies that parseBody only records as invalid or unsupported.
+func bodyFormat(body string) string {
+	r, size := utf8.DecodeRuneInString(body)
+	if name, ok := dataTypeFormats[string(r)]; ok {
+		return name
+	}
+	if _, ok := unsupportedFormats[string(r)]; !ok && r != ',' && !unknownDataType(r) && positionBang(body[size:]) >= 0 {
+		return "position"
+	}
+	return ""
+}
+
 // parseBody parses body of APRS packet
 func (p *Parsed) parseBody(body string) error {
+	if p.limits.expired() {
+		return ErrParseTimeout
+	}
+
 	// Get type (first rune)
 	runes := []rune(body)
 	if len(runes) == 0 {
@@ -79,6 +114,7 @@ func (p *Parsed) parseBody(body string) error {
 	}
 	packetType := string(runes[0:1])
 	body = string(runes[1:])
+	p.DataType = packetType
 
 	// Only status reports may have an empty payload (e.g. ">").
 	if utils.StringLen(body) == 0 && packetType != ">" {
@@ -141,6 +177,9 @@ func (p *Parsed) parseBody(body string) error {
 		if p.Format == "bulletin" || p.Format == "group-bulletin" || p.Format == "announcement" {
 			p.PacketType |= TypeBulletin
 		}
+		if p.Format == "telemetry" {
+			p.PacketType |= TypeTelemetry
+		}
 	// Positionless weather report ("_" classic, "#"/"*" raw)
 	case "_", "#", "*":
 		if _, err := p.parseWeather(body); err != nil {
@@ -162,7 +201,10 @@ func (p *Parsed) parseBody(body string) error {
 	default:
 		// Some clients omit the leading data-type char; if an embedded '!'
 		// appears early, treat the body as a position report.
-		if pos := strings.Index(body, "!"); pos >= 0 && pos < 40 {
+		if unknownDataType(runes[0]) {
+			p.Format = "unknown"
+			p.Body = body
+		} else if positionBang(body) >= 0 {
 			if err := p.parsePosition(packetType, body); err != nil {
 				return err
 			}
@@ -203,7 +245,7 @@ func (p *Parsed) parseBody(body string) error {
 
 // cwopCallRe matches CWOP station callsigns: two letters from C..F (the
 // CWOP-assigned ranges) followed by 4+ digits, e.g. CW1234, DW5678, EW0001.
-var cwopCallRe = regexp.MustCompile(`(?i)^[CDEFGH]W\d{3,}$`)
+var cwopCallRe = aprsutils.CompiledRegexps.MustCompile(`(?i)^[CDEFGH]W\d{3,}$`)
 
 // isCWOP reports whether a (weather) packet originates from a CWOP station.
 func isCWOP(p *Parsed) bool {
@@ -20,3 +20,19 @@ var unsupportedFormats = map[string]string{
 	"]":  "unused",
 	"^":  "unused",
 }
+
+// unknownDataType reports whether r is a data type identifier the spec leaves
+// unassigned ('"', '|', '~') or that is no printable ASCII at all (Mic-E's
+// '‘' aside). Such packets are recorded as format "unknown". Letters and
+// digits are unassigned too, but they begin the free-text beacons that may
+// embed a '!' position, so they are left to that heuristic.
+func unknownDataType(r rune) bool {
+	switch {
+	case r == '"' || r == '|' || r == '~':
+		return true
+	case r == '‘':
+		return false
+	default:
+		return r < 0x20 || r > 0x7e
+	}
+}
@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// PositionReport describes an uncompressed position report to encode.
+type PositionReport struct {
+	Lat            float64 // decimal degrees, -90..90
+	Lon            float64 // decimal degrees, -180..180
+	SymbolTable    string  // "/", "\" or an overlay character
+	Symbol         string  // symbol code
+	Comment        string
+	MessageCapable bool       // station can receive messages
+	Timestamp      *time.Time // nil for a report without timestamp
+}
+
+// PositionDataType returns the data type identifier for a position report:
+// '!' (no timestamp, no messaging), '=' (no timestamp, messaging), '/'
+// (timestamp, no messaging) or '@' (timestamp, messaging).
+func PositionDataType(messageCapable bool, timestamped bool) string {
+	switch {
+	case timestamped && messageCapable:
+		return "@"
+	case timestamped:
+		return "/"
+	case messageCapable:
+		return "="
+	default:
+		return "!"
+	}
+}
+
+// EncodePosition builds the information field of an uncompressed position
+// report, choosing the data type identifier from MessageCapable and
+// Timestamp so that parsing it back preserves both. A timestamp is written in
+// the zulu day/hour/minute form ("ddhhmmz").
+func EncodePosition(r PositionReport) (string, error) {
+	pos, err := encodePositionBody(r)
+	if err != nil {
+		return "", err
+	}
+
+	info := PositionDataType(r.MessageCapable, r.Timestamp != nil)
+	if r.Timestamp != nil {
+		info += encodeDHM(*r.Timestamp)
+	}
+	return info + pos, nil
+}
+
+// ObjectReport describes an object or item report to encode. A killed object
+// or item still carries its last position, so that receivers know where to
+// remove it from.
+type ObjectReport struct {
+	Name     string // object: 1-9 characters; item: 3-9, without '!' or '_'
+	Killed   bool
+	Position PositionReport // MessageCapable is not used
+}
+
+// EncodeObject builds the information field of an object report,
+// ";NAME     *ddhhmmz<position>" ('_' instead of '*' when killed). The name is
+// padded to 9 characters; objects always carry a timestamp, so
+// Position.Timestamp is required and written in the zulu ddhhmmz form.
+func EncodeObject(r ObjectReport) (string, error) {
+	if r.Name == "" || len(r.Name) > 9 || strings.ContainsFunc(r.Name, nonPrintable) {
+		return "", errors.New("object name must be 1-9 printable characters")
+	}
+	if r.Position.Timestamp == nil {
+		return "", errors.New("object report needs a timestamp")
+	}
+	pos, err := encodePositionBody(r.Position)
+	if err != nil {
+		return "", err
+	}
+
+	flag := "*"
+	if r.Killed {
+		flag = "_"
+	}
+	return fmt.Sprintf(";%-9s%s%s%s", r.Name, flag, encodeDHM(*r.Position.Timestamp), pos), nil
+}
+
+// EncodeItem builds the information field of an item report, ")NAME!<position>"
+// ('_' instead of '!' when killed). Items carry no timestamp, so
+// Position.Timestamp must be nil.
+func EncodeItem(r ObjectReport) (string, error) {
+	if !itemNameRe.MatchString(r.Name+"!") || len(r.Name) > 9 {
+		return "", errors.New("item name must be 3-9 printable characters other than '!' and '_'")
+	}
+	if r.Position.Timestamp != nil {
+		return "", errors.New("item reports carry no timestamp")
+	}
+	pos, err := encodePositionBody(r.Position)
+	if err != nil {
+		return "", err
+	}
+
+	flag := "!"
+	if r.Killed {
+		flag = "_"
+	}
+	return ")" + r.Name + flag + pos, nil
+}
+
+// encodePositionBody formats the uncompressed position, symbol and comment
+// shared by position, object and item reports.
+func encodePositionBody(r PositionReport) (string, error) {
+	if r.Lat < -90 || r.Lat > 90 || r.Lon < -180 || r.Lon > 180 {
+		return "", errors.New("coordinates out of range")
+	}
+	if len(r.SymbolTable) != 1 || len(r.Symbol) != 1 {
+		return "", errors.New("symbol table and code must be one character each")
+	}
+	return encodeLat(r.Lat) + r.SymbolTable + encodeLon(r.Lon) + r.Symbol + r.Comment, nil
+}
+
+// encodeDHM formats a timestamp in the zulu day/hour/minute form "ddhhmmz".
+func encodeDHM(t time.Time) string {
+	return t.UTC().Format("021504") + "z"
+}
+
+// nonPrintable reports whether r is outside printable ASCII.
+func nonPrintable(r rune) bool {
+	return r < 0x20 || r > 0x7e
+}
+
+// ParseAndCanonicalize parses packet and also returns a canonical form of it,
+// so that packets differing only in formatting compare equal. The header is
+// rebuilt from the upper-cased From, To and Path. A position report (formats
+// "uncompressed", "compressed" and "mic-e", weather excluded) is re-encoded
+// with EncodePosition, writing course/speed and altitude back as the "CSE/SPD"
+// and "/A=" extensions ahead of the trimmed comment. Any other packet keeps
+// its information field, trimmed of trailing spaces.
+//
+// Canonicalization is lossy for formats that do not round-trip: whatever the
+// uncompressed encoding cannot carry (Mic-E message bits, compressed radio
+// range, PHG/RNG, DAO, telemetry, ambiguity, timestamp seconds, precision
+// below 1/100 minute) is dropped. Keep the raw packet; the canonical form is a
+// dedup key.
+func ParseAndCanonicalize(packet string, options ...Option) (Parsed, string, error) {
+	p, err := Parse(packet, options...)
+	if err != nil {
+		return p, "", err
+	}
+
+	header := strings.ToUpper(p.From + ">" + p.To)
+	for _, hop := range p.Path {
+		header += "," + strings.ToUpper(hop)
+	}
+
+	_, info, _ := strings.Cut(strings.Trim(packet, "\r\n"), ":")
+	info = strings.TrimRight(info, " ")
+
+	switch p.Format {
+	case "uncompressed", "compressed", "mic-e":
+		if !p.PacketType.Has(TypeWeather) && len(p.Symbol) == 2 {
+			if encoded, err := EncodePosition(canonicalReport(p)); err == nil {
+				info = encoded
+			}
+		}
+	}
+
+	return p, header + ":" + info, nil
+}
+
+// canonicalReport builds the PositionReport ParseAndCanonicalize encodes for
+// a parsed position.
+func canonicalReport(p Parsed) PositionReport {
+	var ext string
+	if p.Course != 0 || p.Speed != 0 || p.IsFixed {
+		ext = fmt.Sprintf("%03d/%03d", int(math.Round(p.Course)), int(math.Round(p.Speed/1.852)))
+	}
+	if p.AltitudeSource != AltitudeNone {
+		feet := int(math.Round(p.Altitude / 0.3048))
+		if feet < 0 {
+			ext += fmt.Sprintf("/A=-%05d", -feet)
+		} else {
+			ext += fmt.Sprintf("/A=%06d", feet)
+		}
+	}
+
+	r := PositionReport{
+		Lat:            p.Lat,
+		Lon:            p.Lon,
+		SymbolTable:    p.Symbol[1],
+		Symbol:         p.Symbol[0],
+		Comment:        ext + strings.TrimSpace(p.Comment),
+		MessageCapable: p.MessageCapable,
+	}
+	if p.RawTimestamp != "" && p.Timestamp != 0 {
+		ts := time.Unix(int64(p.Timestamp), 0)
+		r.Timestamp = &ts
+	}
+	return r
+}
+
+// encodeLat formats a latitude as DDMM.mmN/S.
+func encodeLat(lat float64) string {
+	deg, hundredths := splitMinutes(lat)
+	hemi := "N"
+	if lat < 0 {
+		hemi = "S"
+	}
+	return fmt.Sprintf("%02d%02d.%02d%s", deg, hundredths/100, hundredths%100, hemi)
+}
+
+// encodeLon formats a longitude as DDDMM.mmE/W.
+func encodeLon(lon float64) string {
+	deg, hundredths := splitMinutes(lon)
+	hemi := "E"
+	if lon < 0 {
+		hemi = "W"
+	}
+	return fmt.Sprintf("%03d%02d.%02d%s", deg, hundredths/100, hundredths%100, hemi)
+}
+
+// splitMinutes splits an absolute coordinate into whole degrees and
+// hundredths of minutes, rounding so that 59.995' carries into the degree.
+func splitMinutes(v float64) (int, int) {
+	total := int(math.Round(math.Abs(v) * 60 * 100))
+	return total / 6000, total % 6000
+}
@@ -5,8 +5,7 @@ import (
 	"strconv"
 	"strings"
 
-	"go.gh.ink/regexp"
-
+	"github.com/APRSCN/aprsutils"
 	"github.com/APRSCN/aprsutils/utils"
 )
 
@@ -15,7 +14,11 @@ import (
 // Item format (aprs101.pdf ch. 11):
 //
 //	)DDDDDDDDD!....   where the name is 3-9 chars and the flag is '!' (live) or '_' (killed)
-var itemNameRe = regexp.MustCompile(`^([\x20-\x7e]{3,9})(!|_)`)
+//
+// The name itself may not contain '!' or '_'. Excluding them keeps the match
+// anchored on the first flag, so a compressed position whose base-91 bytes
+// include '!' is not swallowed into the name.
+var itemNameRe = aprsutils.CompiledRegexps.MustCompile(`^([\x20\x22-\x5e\x60-\x7e]{3,9})(!|_)`)
 
 // parseItem parses an APRS item report ( ')' data type ).
 func (p *Parsed) parseItem(body string) error {
@@ -61,7 +64,7 @@ func (p *Parsed) parseQuery(body string) string {
 // telemetryReportRe matches a telemetry data report: T#nnn or Tnnn style.
 //
 //	T#005,199,000,255,073,123,01101001
-var telemetryReportRe = regexp.MustCompile(`^#?(\d{1,3}|MIC)\s*,?(.*)$`)
+var telemetryReportRe = aprsutils.CompiledRegexps.MustCompile(`^#?(\d{1,3}|MIC)\s*,?(.*)$`)
 
 // parseTelemetryReport parses an APRS telemetry data report ( 'T' data type ).
 func (p *Parsed) parseTelemetryReport(body string) string {
@@ -76,7 +79,8 @@ func (p *Parsed) parseTelemetryReport(body string) string {
 	seq := matches[1]
 	fields := strings.Split(matches[2], ",")
 
-	// Sequence number (non-numeric "MIC" stays 0).
+	// Sequence number (non-numeric "MIC" stays 0; RawSeq keeps the token).
+	p.Telemetry.RawSeq = seq
 	if n, err := strconv.Atoi(seq); err == nil {
 		p.Telemetry.Seq = n
 	}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatLog renders p as a single space-separated log line, in the spirit of
+// the packet logs written by aprsc and javAPRSSrv:
+//
+//	<time> <from>><to>[,<path>] <format> <lat> <lon> <text>
+//
+// where:
+//   - time: the packet's own timestamp as RFC 3339 UTC
+//     ("2006-01-02T15:04:05Z"), or "-" when it carries none; prefix the line
+//     with the receive time if you need one for every packet
+//   - format: Parsed.Format, or "-" when unknown
+//   - lat/lon: decimal degrees with 5 decimals, or "-" "-" without a position
+//   - text: the comment, status text or message text, whichever is present
+//     first, with control characters replaced by spaces; omitted when empty
+//
+// It lives in parser rather than the root package because aprsutils cannot
+// import parser.
+func FormatLog(p Parsed) string {
+	var b strings.Builder
+
+	if p.Timestamp != 0 {
+		b.WriteString(time.Unix(int64(p.Timestamp), 0).UTC().Format(time.RFC3339))
+	} else {
+		b.WriteString("-")
+	}
+
+	b.WriteString(" ")
+	b.WriteString(p.From)
+	b.WriteString(">")
+	b.WriteString(p.To)
+	for _, hop := range p.Path {
+		b.WriteString(",")
+		b.WriteString(hop)
+	}
+
+	b.WriteString(" ")
+	if p.Format != "" {
+		b.WriteString(p.Format)
+	} else {
+		b.WriteString("-")
+	}
+
+	if p.HasPosition {
+		b.WriteString(" ")
+		b.WriteString(strconv.FormatFloat(p.Lat, 'f', 5, 64))
+		b.WriteString(" ")
+		b.WriteString(strconv.FormatFloat(p.Lon, 'f', 5, 64))
+	} else {
+		b.WriteString(" - -")
+	}
+
+	text := p.Comment
+	if text == "" {
+		text = p.Status
+	}
+	if text == "" {
+		text = p.MessageText
+	}
+	text = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, text))
+	if text != "" {
+		b.WriteString(" ")
+		b.WriteString(text)
+	}
+
+	return b.String()
+}
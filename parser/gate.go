@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/APRSCN/aprsutils"
+)
+
+// ShouldGateToRF applies the APRS-IS igate rules for passing a packet
+// received from APRS-IS to RF (http://www.aprs-is.net/IGateDetails.aspx) and
+// returns whether it may be gated, or else why not. myCall is the igate's
+// own callsign. A packet is not gated when:
+//
+//   - its RF path carries TCPXX, NOGATE or RFONLY (TCPIP is normal for
+//     internet-originated packets and does not prevent gating)
+//   - an unverified client injected it (qAX)
+//   - it was heard on RF (qAR, qAr, qAO, qAo): the RF side already has it
+//   - it was sent by myCall, or already passed through it (e.g. myCall gated
+//     it from RF in the first place)
+//   - any of that holds for the packet carried in a third-party body
+//
+// Whether the addressee was recently heard on RF, which the rules also
+// require, is left to the caller's station list.
+func ShouldGateToRF(p Parsed, myCall string) (bool, string) {
+	for _, hop := range p.Path {
+		if isQConstruct(hop) {
+			break
+		}
+		switch call := strings.ToUpper(strings.TrimSuffix(hop, "*")); call {
+		case "TCPXX", "NOGATE", "RFONLY":
+			return false, "path contains " + call
+		}
+	}
+	if p.Unverified() {
+		return false, "injected by an unverified client"
+	}
+	if p.FromRF() {
+		return false, "heard on RF"
+	}
+	if aprsutils.SameStation(p.From, myCall, false) {
+		return false, "sent by " + myCall
+	}
+	for _, hop := range p.Path {
+		if aprsutils.SameStation(hop, myCall, false) {
+			return false, "already passed through " + myCall
+		}
+	}
+	if p.SubPacket != nil {
+		if ok, reason := ShouldGateToRF(*p.SubPacket, myCall); !ok {
+			return false, "third-party packet: " + reason
+		}
+	}
+	return true, ""
+}
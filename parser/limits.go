@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"errors"
+	"time"
+)
+
+// Limit errors returned by Parse, one per field of Limits.
+var (
+	ErrInfoTooLong       = errors.New("information field exceeds MaxInfoLength")
+	ErrThirdPartyTooDeep = errors.New("third-party packets nested deeper than MaxThirdPartyDepth")
+	ErrParseTimeout      = errors.New("parse exceeded Timeout")
+)
+
+// Limits bounds the work Parse spends on one packet, to harden it against
+// hostile input. A zero field disables that limit.
+type Limits struct {
+	// MaxInfoLength is the longest information field accepted, in bytes
+	// (ErrInfoTooLong).
+	MaxInfoLength int
+	// MaxThirdPartyDepth is how many third-party ("}") packets may be nested
+	// inside each other (ErrThirdPartyTooDeep).
+	MaxThirdPartyDepth int
+	// Timeout is the time budget of one Parse call, nested packets included
+	// (ErrParseTimeout). It is checked before the body is decoded, between
+	// the comment extension decoders and at the end, so a single step is
+	// never interrupted.
+	Timeout time.Duration
+}
+
+// DefaultLimits are the limits Parse applies unless WithLimits is given: an
+// information field no longer than a whole APRS-IS line (MaxISLineLength),
+// third-party packets nested 3 deep (a real packet is rarely gated more than
+// twice) and no timeout, since every decoder runs in time linear in the
+// already bounded length.
+//
+// Before Limits existed Parse accepted any length and depth, so a plain Parse
+// now rejects packets beyond these bounds. Pass WithLimits(Limits{}) to keep
+// the old unlimited behavior.
+var DefaultLimits = Limits{
+	MaxInfoLength:      MaxISLineLength,
+	MaxThirdPartyDepth: 3,
+}
+
+// WithLimits replaces DefaultLimits for this call.
+func WithLimits(limits Limits) Option {
+	return func(p *config) {
+		p.limits = limits
+	}
+}
+
+// limitState carries the Limits of one Parse call, with the nesting depth
+// and deadline reached so far, into nested third-party packets.
+type limitState struct {
+	Limits
+	depth    int
+	deadline time.Time
+}
+
+// withLimitState makes a nested Parse continue the limits of its parent.
+func withLimitState(s *limitState) Option {
+	return func(p *config) {
+		p.nested = s
+	}
+}
+
+// limitNow is the clock of the parse Timeout; tests replace it.
+var limitNow = time.Now
+
+// newLimitState starts the limits of a top-level Parse call.
+func newLimitState(limits Limits) *limitState {
+	s := &limitState{Limits: limits}
+	if limits.Timeout > 0 {
+		s.deadline = limitNow().Add(limits.Timeout)
+	}
+	return s
+}
+
+// expired reports whether the Timeout has run out. A nil state (a body
+// decoded outside Parse) never expires.
+func (s *limitState) expired() bool {
+	return s != nil && !s.deadline.IsZero() && limitNow().After(s.deadline)
+}
+
+// child returns the state of a third-party packet nested in this one, or
+// ErrThirdPartyTooDeep.
+func (s *limitState) child() (*limitState, error) {
+	if s == nil {
+		return nil, nil
+	}
+	if s.MaxThirdPartyDepth > 0 && s.depth >= s.MaxThirdPartyDepth {
+		return nil, ErrThirdPartyTooDeep
+	}
+	c := *s
+	c.depth++
+	return &c, nil
+}
@@ -3,25 +3,29 @@ package parser
 import (
 	"strings"
 
-	"go.gh.ink/regexp"
 	"go.gh.ink/toolbox/expr"
 
+	"github.com/APRSCN/aprsutils"
 	"github.com/APRSCN/aprsutils/utils"
 )
 
 // Message sub-format regexps, compiled once at package load.
 var (
-	reBulletin     = regexp.MustCompile(`(?i)^BLN([0-9])([a-z0-9_ \-]{5}):(.{0,67})`)
-	reAnnouncement = regexp.MustCompile(`^BLN([A-Z])([a-zA-Z0-9_ \-]{5}):(.{0,67})`)
-	reAddressed    = regexp.MustCompile(`^([a-zA-Z0-9_ \-]{9}):(.*)$`)
+	reBulletin     = aprsutils.CompiledRegexps.MustCompile(`(?i)^BLN([0-9])([a-z0-9_ \-]{5}):(.{0,67})`)
+	reAnnouncement = aprsutils.CompiledRegexps.MustCompile(`^BLN([A-Z])([a-zA-Z0-9_ \-]{5}):(.{0,67})`)
+	// The addressee should be space-padded to 9 chars, but some senders pad
+	// it short or long; accept 1-9 chars plus any padding.
+	reAddressed = aprsutils.CompiledRegexps.MustCompile(`^([a-zA-Z0-9_\-]{1,9})( *):(.*)$`)
 	// NEW reply-ack ack/rej: ackMM}AA
-	reAckRejReply = regexp.MustCompile(`^(ack|rej)([A-Za-z0-9]{2})}([A-Za-z0-9]{2})?$`)
+	reAckRejReply = aprsutils.CompiledRegexps.MustCompile(`^(ack|rej)([A-Za-z0-9]{2})}([A-Za-z0-9]{2})?$`)
 	// Standard ack/rej (aprs101.pdf ch.14): ack12345
-	reAckRej = regexp.MustCompile(`^(ack|rej)([A-Za-z0-9]{1,5})$`)
+	reAckRej = aprsutils.CompiledRegexps.MustCompile(`^(ack|rej)([A-Za-z0-9]{1,5})$`)
 	// NEW message format trailer: text...{MM}AA
-	reMsgNoReply = regexp.MustCompile(`{([A-Za-z0-9]{2})}([A-Za-z0-9]{2})?$`)
+	reMsgNoReply = aprsutils.CompiledRegexps.MustCompile(`{([A-Za-z0-9]{2})}([A-Za-z0-9]{2})?$`)
 	// Old message format trailer: text...{msgNo
-	reMsgNo = regexp.MustCompile(`{([A-Za-z0-9]{1,5})$`)
+	reMsgNo = aprsutils.CompiledRegexps.MustCompile(`{([A-Za-z0-9]{1,5})$`)
+	// Directed query (aprs101.pdf ch.15): ?APRSP, ?APRSV, ?WX? ...
+	reDirectedQuery = aprsutils.CompiledRegexps.MustCompile(`^\?([A-Z]{2,8})\??$`)
 )
 
 // parseMessage parses a message (":") body, populating the relevant Parsed
@@ -42,6 +46,11 @@ func (p *Parsed) parseMessage(body string) {
 		p.MessageText = strings.Trim(m[3], " ")
 		p.BID = m[1]
 		p.Identifier = identifier
+		p.BulletinKind = BulletinKindGeneral
+		if identifier != "" {
+			p.BulletinKind = BulletinKindGroup
+			p.BulletinGroup = strings.ToUpper(identifier)
+		}
 
 	// Announcement: BLN<letter><id>:text
 	case matchN(reAnnouncement, body, 4):
@@ -50,18 +59,22 @@ func (p *Parsed) parseMessage(body string) {
 		p.MessageText = strings.Trim(m[3], " ")
 		p.AID = m[1]
 		p.Identifier = strings.TrimRight(m[2], " ")
+		p.BulletinKind = BulletinKindAnnouncement
 
 	// Addressed message: <9-char addressee>:body
-	case matchN(reAddressed, body, 3):
+	case matchN(reAddressed, body, 4):
 		m := reAddressed.FindStringSubmatch(body)
-		p.Addressee = strings.TrimRight(m[1], " ")
-		p.parseAddressedMessage(m[2])
+		p.Addressee = m[1]
+		if len(m[1])+len(m[2]) != 9 {
+			p.warn("addressee", "addressee not padded to 9 characters")
+		}
+		p.parseAddressedMessage(m[3])
 	}
 }
 
 // matchN reports whether re matches body with at least n submatch groups
 // (including the full match at index 0).
-func matchN(re *regexp.Regexp, body string, n int) bool {
+func matchN(re *aprsutils.Regexp, body string, n int) bool {
 	m := re.FindStringSubmatch(body)
 	return m != nil && len(m) >= n
 }
@@ -77,6 +90,17 @@ func (p *Parsed) parseAddressedMessage(body string) {
 		return
 	}
 
+	// Some stations send the telemetry values themselves ("T#005,...") as a
+	// message body instead of a 'T' packet; decode them the same way.
+	if strings.HasPrefix(body, "T#") {
+		if m := reMsgNo.FindStringSubmatch(body); m != nil {
+			body = trimTrailer(body, 1+utils.StringLen(m[1]))
+			p.MsgNo = m[1]
+		}
+		p.parseTelemetryReport(body[1:])
+		return
+	}
+
 	p.Format = "message"
 
 	switch {
@@ -120,6 +144,11 @@ func (p *Parsed) parseAddressedMessage(body string) {
 	default:
 		p.MessageText = strings.Trim(body, " ")
 	}
+
+	// A message whose text is "?QUERY" is a directed query to the addressee.
+	if m := reDirectedQuery.FindStringSubmatch(p.MessageText); m != nil {
+		p.Query = m[1]
+	}
 }
 
 // trimTrailer removes the trailing removeLen runes (the message-number
@@ -131,3 +160,36 @@ func trimTrailer(body string, removeLen int) string {
 	}
 	return strings.Trim(string([]rune(body)[:utils.StringLen(body)-removeLen]), " ")
 }
+
+// SentMessage identifies a message we transmitted, for matching the
+// acknowledgement that confirms its delivery.
+type SentMessage struct {
+	From      string // our callsign (the ack's addressee)
+	Addressee string // station the message was sent to (the ack's source)
+	MsgNo     string // message number sent as "{MsgNo"
+}
+
+// MatchAck reports whether received is a delivery receipt for sent: a packet
+// from sent.Addressee, addressed to sent.From, that either
+//   - is an ack for the number ("ackNN", or "ackNN}AA" in reply-ack form), or
+//   - is a reply-ack message whose trailing ack number is ours
+//     ("text{MM}NN").
+//
+// Callsigns compare with aprsutils.SameStation, message numbers exactly. A rej is not
+// a receipt.
+func MatchAck(sent SentMessage, received Parsed) bool {
+	if sent.MsgNo == "" || received.Format != "message" ||
+		!aprsutils.SameStation(received.From, sent.Addressee, false) ||
+		!aprsutils.SameStation(received.Addressee, sent.From, false) {
+		return false
+	}
+
+	switch received.Response {
+	case "ack":
+		return received.MsgNo == sent.MsgNo || received.AckMsgNo == sent.MsgNo
+	case "":
+		return received.AckMsgNo == sent.MsgNo
+	default:
+		return false
+	}
+}
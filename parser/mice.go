@@ -6,8 +6,6 @@ import (
 	"strconv"
 	"strings"
 
-	"go.gh.ink/regexp"
-
 	"github.com/APRSCN/aprsutils"
 	"github.com/APRSCN/aprsutils/utils"
 )
@@ -48,12 +46,12 @@ func (p *Parsed) parseMicE(dstCall string, body string) (string, error) {
 		return "", errors.New("packet data field is too short")
 	}
 
-	re1 := regexp.MustCompile(`^[0-9A-Z]{3}[0-9L-Z]{3}$`)
+	re1 := aprsutils.CompiledRegexps.MustCompile(`^[0-9A-Z]{3}[0-9L-Z]{3}$`)
 	if !re1.MatchString(dstCall) {
 		return "", errors.New("invalid dstCall")
 	}
 
-	re2 := regexp.MustCompile(`^[&-\x7f][&-a][\x1c-\x7f]{2}[\x1c-\x7d][\x1c-\x7f][\x21-\x7e][/\\0-9A-Z]`)
+	re2 := aprsutils.CompiledRegexps.MustCompile(`^[&-\x7f][&-a][\x1c-\x7f]{2}[\x1c-\x7d][\x1c-\x7f][\x21-\x7e][/\\0-9A-Z]`)
 	if !re2.MatchString(body) {
 		return "", errors.New("invalid data format")
 	}
@@ -78,7 +76,7 @@ func (p *Parsed) parseMicE(dstCall string, body string) (string, error) {
 	}
 
 	// Determine position ambiguity
-	re3 := regexp.MustCompile(`^\d+( *)$`)
+	re3 := aprsutils.CompiledRegexps.MustCompile(`^\d+( *)$`)
 	matches := re3.FindStringSubmatch(tempDstCall)
 	if matches == nil {
 		return "", errors.New("invalid latitude ambiguity")
@@ -112,12 +110,12 @@ func (p *Parsed) parseMicE(dstCall string, body string) (string, error) {
 		latitude = -latitude
 	}
 
-	p.Lat = latitude
+	p.Lat = normalizeLat(latitude)
 
 	// Parse message bits
-	mBits := regexp.MustCompile("[0-9L]").ReplaceAllString(string([]rune(dstCall)[0:3]), "0")
-	mBits = regexp.MustCompile("[P-Z]").ReplaceAllString(mBits, "1")
-	mBits = regexp.MustCompile("[A-K]").ReplaceAllString(mBits, "2")
+	mBits := aprsutils.CompiledRegexps.MustCompile("[0-9L]").ReplaceAllString(string([]rune(dstCall)[0:3]), "0")
+	mBits = aprsutils.CompiledRegexps.MustCompile("[P-Z]").ReplaceAllString(mBits, "1")
+	mBits = aprsutils.CompiledRegexps.MustCompile("[A-K]").ReplaceAllString(mBits, "2")
 
 	p.MBits = mBits
 
@@ -180,7 +178,7 @@ func (p *Parsed) parseMicE(dstCall string, body string) (string, error) {
 		longitude = -longitude
 	}
 
-	p.Lon = longitude
+	p.Lon = normalizeLon(longitude)
 
 	// Parse speed and course
 	speed := float64(int(bodyRunes[3])-28) * 10
@@ -196,6 +194,9 @@ func (p *Parsed) parseMicE(dstCall string, body string) (string, error) {
 	if course >= 400 {
 		course -= 400
 	}
+	if course > 360 {
+		p.warn("course", "course "+strconv.Itoa(int(course))+" out of range 0-360")
+	}
 
 	speed *= 1.852
 	p.Speed = speed
@@ -205,7 +206,7 @@ func (p *Parsed) parseMicE(dstCall string, body string) (string, error) {
 		body = string([]rune(body)[8:])
 
 		// Check for optional 2 or 5 channel telemetry
-		re4 := regexp.MustCompile(`^('[0-9a-f]{10}|` + "`" + `[0-9a-f]{4})(.*)$`)
+		re4 := aprsutils.CompiledRegexps.MustCompile(`^('[0-9a-f]{10}|` + "`" + `[0-9a-f]{4})(.*)$`)
 		matches := re4.FindStringSubmatch(body)
 		if len(matches) >= 3 {
 			hexData, remainingBody := matches[1], matches[2]
@@ -227,7 +228,11 @@ func (p *Parsed) parseMicE(dstCall string, body string) (string, error) {
 			body = remainingBody
 		}
 
-		re5 := regexp.MustCompile(`^(.*)([!-{]{3})}(.*)$`)
+		// Optional "xxx}" altitude. Take the first one: it belongs near the
+		// start of the status text, while a later "}" may be free text. The
+		// characters cannot overlap base-91 comment telemetry ("|...|"), so
+		// the two are found independently whichever comes first.
+		re5 := aprsutils.CompiledRegexps.MustCompile(`^(.*?)([!-{]{3})}(.*)$`)
 		matches = re5.FindStringSubmatch(body)
 		if len(matches) >= 4 {
 			bodyPart, altitude, extra := matches[1], matches[2], matches[3]
@@ -237,12 +242,11 @@ func (p *Parsed) parseMicE(dstCall string, body string) (string, error) {
 			}
 			altitudeValue := altitudeBase91 - 10000
 			p.Altitude = float64(altitudeValue)
+			p.AltitudeSource = AltitudeMicE
 			body = bodyPart + extra
 		}
 
-		body = p.parseCommentTelemetry(body)
-
-		body = p.parseDAO(body)
+		body = p.runCommentSteps(body, commentSteps[1:])
 
 		p.Comment = strings.Trim(body, " ")
 	}
@@ -1,6 +1,10 @@
 package parser
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/APRSCN/aprsutils"
+)
 
 // parseInvalid parses invalid APRS packet
 func (p *Parsed) parseInvalid(body string) string {
@@ -9,6 +13,27 @@ func (p *Parsed) parseInvalid(body string) string {
 	return body
 }
 
+// UserDefinedApps names the application behind a user-defined ("{UX")
+// packet (aprs101.pdf ch. 18; assignments at
+// http://www.aprs.org/aprs11/format-id.txt and expfmts.txt). Keys are either
+// the full "{UX" identifier, for a single packet type, or "{U", for every
+// type of that user ID; the full identifier wins. The map is exported so
+// applications can register the IDs they know of.
+var UserDefinedApps = map[string]string{
+	"{{":  "experimental",
+	"{D":  "Dire Wolf",
+	"{DT": "Dire Wolf raw touch tone data",
+	"{DM": "Dire Wolf Morse code data",
+}
+
+// userDefinedApp looks up the application of user ID u and packet type x.
+func userDefinedApp(u, x string) string {
+	if app, ok := UserDefinedApps["{"+u+x]; ok && x != "" {
+		return app
+	}
+	return UserDefinedApps["{"+u]
+}
+
 // parseUserDefined parses user defined APRS packet
 func (p *Parsed) parseUserDefined(body string) string {
 	p.Format = "user-defined"
@@ -24,12 +49,22 @@ func (p *Parsed) parseUserDefined(body string) string {
 	} else {
 		p.Body = ""
 	}
+	if p.ID != "" {
+		p.UserDefinedApp = userDefinedApp(p.ID, p.Type)
+	}
 	return body
 }
 
 // parseStatus parses status packet
 func (p *Parsed) parseStatus(body string) string {
 	p.Format = "status"
+
+	// A status report may begin with a zulu DHM timestamp (ddhhmmz); other
+	// timestamp forms are not allowed here and stay part of the text.
+	if aprsutils.CompiledRegexps.MustCompile(`^\d{6}z`).MatchString(body) {
+		body, _ = p.parseTimeStamp(">", body)
+	}
+
 	p.Status = strings.Trim(body, " ")
 	return body
 }
@@ -1,5 +1,7 @@
 package parser
 
+import "time"
+
 // PacketType is a bitmask of the high-level packet category, used by type
 // filters (t/...).
 type PacketType uint32
@@ -24,63 +26,138 @@ const (
 // Has reports whether the given type bit is set.
 func (t PacketType) Has(b PacketType) bool { return t&b != 0 }
 
+// BulletinKind classifies a bulletin message (aprs101.pdf ch. 14).
+type BulletinKind string
+
+const (
+	BulletinKindNone         BulletinKind = ""             // not a bulletin
+	BulletinKindGeneral      BulletinKind = "general"      // BLN0-BLN9, numbered
+	BulletinKindGroup        BulletinKind = "group"        // BLN<n><group>, e.g. BLN1WX
+	BulletinKindAnnouncement BulletinKind = "announcement" // BLNA-BLNZ
+)
+
+// AltitudeSource records which part of a packet Parsed.Altitude came from.
+//
+// When a packet carries more than one altitude, the one encoded by the
+// position format itself (compressed cs bytes, Mic-E "xxx}") wins over a
+// comment "/A=nnnnnn", which is still stripped from the comment.
+type AltitudeSource string
+
+const (
+	AltitudeNone       AltitudeSource = ""           // no altitude decoded
+	AltitudeCompressed AltitudeSource = "compressed" // compressed position cs bytes
+	AltitudeComment    AltitudeSource = "comment"    // "/A=nnnnnn" in the comment
+	AltitudeMicE       AltitudeSource = "mic-e"      // Mic-E "xxx}" altitude
+)
+
+// Warning is a non-fatal issue met while parsing a packet, collected when
+// Parse is called with WithWarnings.
+type Warning struct {
+	Field   string // Parsed field (or weather key) concerned
+	Message string
+}
+
+// warn records a non-fatal parse issue.
+func (p *Parsed) warn(field, message string) {
+	p.Warnings = append(p.Warnings, Warning{Field: field, Message: message})
+}
+
 // Parsed is a struct that storage parsed APRS packet
 type Parsed struct {
-	Raw            string
-	From           string
-	To             string
-	Path           []string
-	Format         string
-	PacketType     PacketType
-	HasPosition    bool
-	Symbol         []string
-	Lat            float64
-	Lon            float64
-	Comment        string
-	MessageCapable bool
-	ObjectName     string
-	ObjectFormat   string
-	Alive          bool
-	RawTimestamp   string
-	Timestamp      int
-	GPSFixStatus   bool
-	Altitude       float64
-	Course         float64
-	Speed          float64
-	RadioRange     float64
-	PosAmbiguity   int
-	Bearing        int
-	Title          string
-	NRQ            int
-	PHG            string
-	PHGPower       float64
-	PHGHeight      float64
-	PHGGain        float64
-	PHGDir         string
-	PHGRange       float64
-	PHGRate        int
-	RNG            float64
-	DAODatumByte   string
-	Telemetry      TelemetryData
-	TelemetryMicE  []int
-	TPARM          []string
-	TUNIT          []string
-	TEQNS          [][]float64
-	TBITS          string
-	Weather        map[string]float64
-	SubPacket      *Parsed
-	Body           string
-	ID             string
-	Type           string
-	Status         string
-	MessageText    string
-	AID            string
-	BID            string
-	Identifier     string
-	Addressee      string
-	Response       string
-	MsgNo          string
-	AckMsgNo       string
-	MType          string
-	MBits          string
+	Raw             string
+	From            string
+	To              string
+	Path            []string
+	RawPath         string // path as received, before cleaning ("" when none)
+	Format          string
+	DataType        string // data type identifier: the first character of the information field
+	PacketType      PacketType
+	HasPosition     bool
+	Symbol          []string
+	Lat             float64
+	Lon             float64
+	Comment         string
+	Truncated       bool
+	Warnings        []Warning
+	ControlChars    bool
+	MessageCapable  bool
+	ObjectName      string
+	ObjectFormat    string
+	Alive           bool
+	RawTimestamp    string
+	Timestamp       int
+	TimestampZulu   bool       // Timestamp was sent in UTC ('z', 'h', MDHM), not local ('/')
+	TimestampLocal  *LocalTime // a local ('/') timestamp as sent, see WithAssumedTimezone
+	GPSFixStatus    bool
+	CompressionInfo *CompressionInfo
+	Altitude        float64
+	AltitudeSource  AltitudeSource
+	Course          float64
+	Speed           float64
+	RawCourseSpeed  string // verbatim "CSE/SPD" data extension, e.g. "088/036"
+	IsFixed         bool   // CSE/SPD was 000/000: a fixed station
+	RadioRange      float64
+	PosAmbiguity    int
+	Bearing         int
+	Title           string
+	NRQ             int
+	PHG             string
+	PHGPower        float64
+	PHGHeight       float64
+	PHGGain         float64
+	PHGDir          string
+	PHGRange        float64
+	PHGRate         int           // PHGR rate digit: beacons per hour (0-9, A-Z = 10-35)
+	PHGInterval     time.Duration // time between beacons implied by PHGRate
+	RNG             float64
+	DAODatumByte    string
+	DAODatum        string
+	SignalReport    *SignalReport
+	Telemetry       TelemetryData
+	TelemetryMicE   []int
+	TPARM           []string
+	TUNIT           []string
+	TEQNS           [][]float64
+	TBITS           string
+	Weather         map[string]float64
+	WeatherTyped    *WeatherReport
+	StationRole     string // heuristic role of the sender (WithStationRole): RoleMobile, RoleWeather, ...
+	SubPacket       *Parsed
+	Body            string
+	ID              string
+	UserDefinedApp  string
+	Type            string
+	Status          string
+	MessageText     string
+	AID             string
+	BID             string
+	Identifier      string
+	BulletinKind    BulletinKind
+	BulletinGroup   string
+	Addressee       string
+	Response        string
+	Query           string
+	MsgNo           string
+	AckMsgNo        string
+	MType           string
+	MBits           string
+
+	// refTime completes partial timestamps (WithReferenceTime); zero means
+	// the current time.
+	refTime time.Time
+	// assumedZone is the zone of local timestamps (WithAssumedTimezone).
+	assumedZone *time.Location
+	// limits holds the Limits of the Parse call decoding this packet.
+	limits *limitState
+	// conf is the configuration of that Parse call, reused for a
+	// third-party packet's SubPacket.
+	conf *config
+}
+
+// now returns the reference time for completing partial timestamps.
+func (p *Parsed) now() time.Time {
+	if p.refTime.IsZero() {
+		return time.Now()
+	}
+	return p.refTime
 }
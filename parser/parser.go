@@ -3,22 +3,136 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
-	"go.gh.ink/regexp"
+	"go.gh.ink/toolbox/expr"
 
+	"github.com/APRSCN/aprsutils"
 	"github.com/APRSCN/aprsutils/utils"
 )
 
+// Structural errors returned by Parse. When the header itself is valid it is
+// still decoded, so callers maximizing yield can keep From/To/Path.
+var (
+	ErrEmptyPacket = errors.New("packet is empty")
+	ErrNoBody      = errors.New("packet has no body")
+	ErrEmptyHead   = errors.New("packet head is empty")
+	ErrEmptyBody   = errors.New("packet body is empty")
+)
+
 // config provides parser config options
 type config struct {
 	disableToCallsignValidate bool
+	typedWeather              bool
+	commentLimit              int
+	stripControlChars         bool
+	roundCoords               bool
+	coordDecimals             int
+	warnings                  bool
+	enabledFormats            map[string]bool
+	refTime                   time.Time
+	assumedZone               *time.Location
+	stationRole               bool
+	limits                    Limits
+	nested                    *limitState
 }
 
+// MaxCommentLength is the longest comment the APRS spec allows after a
+// position report (aprs101.pdf ch. 8).
+const MaxCommentLength = 43
+
 // Option provides a basic option type
 type Option func(*config)
 
+// WithCommentLimit caps Parsed.Comment at limit characters, setting
+// Parsed.Truncated when it had to cut. A zero or negative limit selects
+// MaxCommentLength.
+func WithCommentLimit(limit int) Option {
+	return func(p *config) {
+		if limit <= 0 {
+			limit = MaxCommentLength
+		}
+		p.commentLimit = limit
+	}
+}
+
+// WithStripControlChars removes stray control characters (see
+// isStrayControl) before parsing, so a NUL or bell injected by a buggy TNC
+// does not derail decoding. Parsed.Raw keeps the original bytes and
+// Parsed.ControlChars is set either way.
+func WithStripControlChars() Option {
+	return func(p *config) {
+		p.stripControlChars = true
+	}
+}
+
+// WithCoordinatePrecision rounds Parsed.Lat and Parsed.Lon to decimals
+// decimal places, so that the same position decoded from compressed and
+// uncompressed reports compares equal. 4 matches the 0.01' resolution of an
+// uncompressed position. A negative value is treated as 0.
+func WithCoordinatePrecision(decimals int) Option {
+	return func(p *config) {
+		p.roundCoords = true
+		p.coordDecimals = max(decimals, 0)
+	}
+}
+
+// WithWarnings keeps the non-fatal issues met while decoding the body (values
+// out of range, unreadable fields, malformed timestamps, ...) in
+// Parsed.Warnings. Without it they are discarded; the decoded fields are the
+// same either way.
+func WithWarnings() Option {
+	return func(p *config) {
+		p.warnings = true
+	}
+}
+
+// WithEnabledFormats restricts decoding to the named packet types: "position",
+// "mic-e", "object", "item", "message", "status", "telemetry", "weather",
+// "thirdparty", "user-defined", "query" and "nmea". The header of every
+// packet is still decoded, but the body of any other type is skipped: Format
+// is set to its type name and Parse returns with no error. This saves the
+// work, and the attack surface, of sub-parsers an application ignores.
+func WithEnabledFormats(formats ...string) Option {
+	return func(p *config) {
+		p.enabledFormats = make(map[string]bool, len(formats))
+		for _, f := range formats {
+			p.enabledFormats[f] = true
+		}
+	}
+}
+
+// WithReferenceTime sets the time partial timestamps are completed against
+// (default: now). Its location is not used: local ('/') timestamps are only
+// converted in the zone given by WithAssumedTimezone.
+func WithReferenceTime(t time.Time) Option {
+	return func(p *config) {
+		p.refTime = t
+	}
+}
+
+// WithAssumedTimezone sets the time zone local ('/') timestamps are taken
+// in. APRS does not say which zone the sender's clock is in, so without it a
+// '/' timestamp is not converted: Parsed.Timestamp stays 0 and only
+// Parsed.TimestampLocal is set.
+func WithAssumedTimezone(loc *time.Location) Option {
+	return func(p *config) {
+		p.assumedZone = loc
+	}
+}
+
+// WithTypedWeather additionally fills Parsed.WeatherTyped for packets that
+// carry weather data
+func WithTypedWeather() Option {
+	return func(p *config) {
+		p.typedWeather = true
+	}
+}
+
 // WithDisableToCallsignValidate disables to callsign validate
 func WithDisableToCallsignValidate() Option {
 	return func(p *config) {
@@ -30,6 +144,7 @@ func Parse(packet string, options ...Option) (Parsed, error) {
 	// Create config
 	conf := &config{
 		disableToCallsignValidate: false,
+		limits:                    DefaultLimits,
 	}
 
 	// Apply options
@@ -42,24 +157,43 @@ func Parse(packet string, options ...Option) (Parsed, error) {
 
 	// Save raw packet
 	parsed.Raw = packet
+	parsed.refTime = conf.refTime
+	parsed.assumedZone = conf.assumedZone
+	parsed.conf = conf
+	parsed.limits = conf.nested
+	if parsed.limits == nil {
+		parsed.limits = newLimitState(conf.limits)
+	}
 
 	// Check packet content
 	if packet == "" {
-		return *parsed, errors.New("packet is empty")
+		return *parsed, ErrEmptyPacket
 	}
 
 	// Trim
 	packet = strings.Trim(packet, "\r\n")
 
-	// Split head and body
+	// Flag (and optionally strip) stray control characters
+	if strings.IndexFunc(packet, isStrayControl) >= 0 {
+		parsed.ControlChars = true
+		if conf.stripControlChars {
+			packet = strings.Map(func(r rune) rune {
+				return expr.Ternary(isStrayControl(r), -1, r)
+			}, packet)
+		}
+	}
+
+	// Split head and body. A line without the ':' separator may still be a
+	// bare header; decode it if so but report the missing body.
 	head, body, ok := utils.SplitOnce(packet, ":")
 	if !ok {
-		return *parsed, errors.New("packet has no body")
+		_ = parsed.parseHeader(packet, conf)
+		return *parsed, ErrNoBody
 	}
 
-	// Check body
-	if utils.StringLen(head) == 0 || utils.StringLen(body) == 0 {
-		return *parsed, errors.New("packet head or body is empty")
+	// Check head
+	if utils.StringLen(head) == 0 {
+		return *parsed, ErrEmptyHead
 	}
 
 	// Parse head
@@ -67,14 +201,160 @@ func Parse(packet string, options ...Option) (Parsed, error) {
 		return *parsed, err
 	}
 
+	// Check body
+	if utils.StringLen(body) == 0 {
+		return *parsed, ErrEmptyBody
+	}
+	if n := parsed.limits.MaxInfoLength; n > 0 && len(body) > n {
+		return *parsed, ErrInfoTooLong
+	}
+
+	// Skip packet types that are not enabled
+	if conf.enabledFormats != nil {
+		if name := bodyFormat(body); name != "" && !conf.enabledFormats[name] {
+			parsed.Format = name
+			return *parsed, nil
+		}
+	}
+
 	// Parse body
-	if err := parsed.parseBody(body); err != nil {
+	err := parsed.parseBody(body)
+	if !conf.warnings {
+		parsed.Warnings = nil
+	}
+	if err == nil && parsed.limits.expired() {
+		err = ErrParseTimeout
+	}
+	if err != nil {
 		return *parsed, err
 	}
 
+	// Cap comment
+	if conf.commentLimit > 0 && utils.StringLen(parsed.Comment) > conf.commentLimit {
+		parsed.Comment = string([]rune(parsed.Comment)[:conf.commentLimit])
+		parsed.Truncated = true
+	}
+
+	// Round coordinates
+	if conf.roundCoords {
+		scale := math.Pow10(conf.coordDecimals)
+		parsed.Lat = math.Round(parsed.Lat*scale) / scale
+		parsed.Lon = math.Round(parsed.Lon*scale) / scale
+	}
+
+	// Typed weather
+	if conf.typedWeather && len(parsed.Weather) > 0 {
+		parsed.WeatherTyped = newWeatherReport(parsed.Weather)
+	}
+
+	// Station role
+	if conf.stationRole {
+		parsed.StationRole = stationRole(parsed)
+	}
+
 	return *parsed, nil
 }
 
+// isStrayControl reports whether r is a control character with no place in
+// a packet. 0x1c-0x1f and DEL are excluded because Mic-E encodes data bytes
+// there, and TAB because it turns up in ordinary comment and status text.
+func isStrayControl(r rune) bool {
+	return r < 0x1c && r != '\t'
+}
+
+// ParseBytes parses a packet held in a byte slice, such as a line read from a
+// socket or file, with the same result as Parse(string(packet), options...).
+// The parsed fields (Raw, Comment, ...) are substrings of the packet and
+// outlive the call, so the bytes are copied into one string up front; the
+// caller may reuse packet afterwards. That single copy is the whole cost over
+// Parse, and no further copies are made.
+func ParseBytes(packet []byte, options ...Option) (Parsed, error) {
+	return Parse(string(packet), options...)
+}
+
+// ParseHeader decodes only the "FROM>TO,PATH" header of packet, skipping the
+// body entirely; use it to route traffic by source/destination without the
+// cost of a full Parse. The header is validated exactly as Parse does, and
+// options such as WithDisableToCallsignValidate apply. The body (everything
+// after the first ':') may be absent.
+func ParseHeader(packet string, options ...Option) (from, to string, path []string, err error) {
+	conf := &config{}
+	for _, opt := range options {
+		opt(conf)
+	}
+
+	if packet == "" {
+		return "", "", nil, ErrEmptyPacket
+	}
+
+	head, _, _ := strings.Cut(strings.Trim(packet, "\r\n"), ":")
+	if utils.StringLen(head) == 0 {
+		return "", "", nil, ErrEmptyHead
+	}
+
+	var p Parsed
+	if err := p.parseHeader(head, conf); err != nil {
+		return "", "", nil, err
+	}
+	return p.From, p.To, p.Path, nil
+}
+
+// IsValid reports whether Parse(packet) would succeed, for dropping garbage
+// before more expensive processing. The header is always checked, but the
+// body is only decoded for data types whose decoding can fail (positions,
+// objects, items, Mic-E, weather, third-party); statuses, messages, queries
+// and the like are accepted once the data type is known. DefaultLimits apply
+// as they do to Parse.
+func IsValid(packet string) bool {
+	head, body, ok := utils.SplitOnce(strings.Trim(packet, "\r\n"), ":")
+	if !ok || head == "" || body == "" {
+		return false
+	}
+	if n := DefaultLimits.MaxInfoLength; n > 0 && len(body) > n {
+		return false
+	}
+
+	p := Parsed{limits: newLimitState(DefaultLimits)}
+	if p.parseHeader(head, &config{}) != nil {
+		return false
+	}
+
+	r, size := utf8.DecodeRuneInString(body)
+	if size == len(body) {
+		return r == '>'
+	}
+	if _, ok := unsupportedFormats[string(r)]; ok {
+		return false
+	}
+	switch r {
+	case ',', '{', '>', '?', 'T', '$', ':':
+		return true
+	}
+	if _, ok := dataTypeFormats[string(r)]; !ok && positionBang(body[size:]) < 0 {
+		return true
+	}
+	return p.parseBody(body) == nil
+}
+
+// ParseAll parses a blob holding several packets, one per line (e.g. a batched
+// UDP datagram or a pasted log). Blank lines are skipped; for every other line
+// the result and its error are stored at the same index of the returned
+// slices.
+func ParseAll(data string, options ...Option) ([]Parsed, []error) {
+	lines := strings.Split(data, "\n")
+	results := make([]Parsed, 0, len(lines))
+	errs := make([]error, 0, len(lines))
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p, err := Parse(line, options...)
+		results = append(results, p)
+		errs = append(errs, err)
+	}
+	return results, errs
+}
+
 // parseTimeStamp parses timestamp from APRS packet
 func (p *Parsed) parseTimeStamp(packetType string, body string) (string, error) {
 	// Check body length
@@ -82,42 +362,44 @@ func (p *Parsed) parseTimeStamp(packetType string, body string) (string, error)
 		return body, errors.New("invalid timestamp format")
 	}
 	// Match
-	matches := regexp.MustCompile(`^((\d{6})(.))$`).FindStringSubmatch(string([]rune(body)[0:7]))
+	matches := aprsutils.CompiledRegexps.MustCompile(`^((\d{6})(.))$`).FindStringSubmatch(string([]rune(body)[0:7]))
 	if len(matches) < 4 {
 		return body, nil
 	}
 
 	rawts, ts, form := matches[1], matches[2], matches[3]
-	utc := time.Now().UTC()
-	local := time.Now()
 	timestamp := 0
 
 	if !(packetType == ">" && form != "z") {
 		body = string([]rune(body)[7:])
 
-		var timeStr string
+		ref := p.now()
+		var t time.Time
 		var err error
 
 		switch form {
 		case "h":
 			// Zulu hhmmss format (UTC).
-			timeStr = fmt.Sprintf("%d%02d%02d%s", utc.Year(), utc.Month(), utc.Day(), ts)
-			timestamp, err = parseTimeStringIn(timeStr, "20060102150405", time.UTC)
+			t, err = resolveHMS(ref.UTC(), ts)
+			p.TimestampZulu = true
 		case "z":
-			// Zulu ddhhmm format (UTC): ts is DDHHMM, seconds = 00.
-			timeStr = fmt.Sprintf("%d%02d%s00", utc.Year(), utc.Month(), ts)
-			timestamp, err = parseTimeStringIn(timeStr, "20060102150405", time.UTC)
+			// Zulu ddhhmm format (UTC).
+			t, err = resolveDHM(ref.UTC(), ts)
+			p.TimestampZulu = true
 		case "/":
-			// Local ddhhmm format: interpret in the host's local timezone
-			// (this is what the '/' form denotes per the APRS spec).
-			timeStr = fmt.Sprintf("%d%02d%s00", local.Year(), local.Month(), ts)
-			timestamp, err = parseTimeStringIn(timeStr, "20060102150405", time.Local)
-		default:
-			timestamp = 0
+			// Local ddhhmm format: the packet does not say which zone, so it
+			// is only converted when one is assumed.
+			if p.TimestampLocal, err = parseLocalTime(ts); err == nil {
+				if loc := p.assumedZone; loc != nil {
+					t, err = resolveDHM(ref.In(loc), ts)
+				}
+			}
 		}
 
 		if err != nil {
-			timestamp = 0
+			p.warn("timestamp", "invalid timestamp "+rawts)
+		} else if !t.IsZero() {
+			timestamp = int(t.Unix())
 		}
 	}
 
@@ -127,6 +409,83 @@ func (p *Parsed) parseTimeStamp(packetType string, body string) (string, error)
 	return body, nil
 }
 
+// parseMDHMTimeStamp parses the MMDDHHMM (UTC) timestamp of a positionless
+// weather report into RawTimestamp and Timestamp. The year is the reference
+// time's, or the previous one if that would put the report more than a day in
+// the future (a December report received in January).
+func (p *Parsed) parseMDHMTimeStamp(ts string) {
+	p.RawTimestamp = ts
+
+	p.TimestampZulu = true
+	utc := p.now().UTC()
+	timestamp, err := parseTimeStringIn(fmt.Sprintf("%d%s00", utc.Year(), ts), "20060102150405", time.UTC)
+	if err == nil && time.Unix(int64(timestamp), 0).After(utc.Add(24*time.Hour)) {
+		timestamp, err = parseTimeStringIn(fmt.Sprintf("%d%s00", utc.Year()-1, ts), "20060102150405", time.UTC)
+	}
+	if err != nil {
+		p.warn("timestamp", "invalid timestamp "+ts)
+		timestamp = 0
+	}
+	p.Timestamp = timestamp
+}
+
+// errInvalidTimestamp reports a timestamp naming an impossible time.
+var errInvalidTimestamp = errors.New("invalid timestamp")
+
+// LocalTime is a local ('/') DDHHMM timestamp as sent: wall-clock time in
+// the sender's time zone, which the packet does not carry.
+type LocalTime struct {
+	Day    int
+	Hour   int
+	Minute int
+}
+
+// parseLocalTime splits a DDHHMM timestamp into its fields.
+func parseLocalTime(ts string) (*LocalTime, error) {
+	day, _ := strconv.Atoi(ts[0:2])
+	hour, _ := strconv.Atoi(ts[2:4])
+	minute, _ := strconv.Atoi(ts[4:6])
+	if day < 1 || day > 31 || hour > 23 || minute > 59 {
+		return nil, errInvalidTimestamp
+	}
+	return &LocalTime{Day: day, Hour: hour, Minute: minute}, nil
+}
+
+// resolveDHM completes a DDHHMM timestamp in ref's zone with ref's month, or
+// the previous month when that would put it more than a day after ref (a
+// report from the 31st received on the 1st).
+func resolveDHM(ref time.Time, ts string) (time.Time, error) {
+	day, _ := strconv.Atoi(ts[0:2])
+	hour, _ := strconv.Atoi(ts[2:4])
+	minute, _ := strconv.Atoi(ts[4:6])
+	if day < 1 || hour > 23 || minute > 59 {
+		return time.Time{}, errInvalidTimestamp
+	}
+	for _, back := range []time.Month{0, 1} {
+		t := time.Date(ref.Year(), ref.Month()-back, day, hour, minute, 0, 0, ref.Location())
+		if t.Day() == day && !t.After(ref.Add(24*time.Hour)) {
+			return t, nil
+		}
+	}
+	return time.Time{}, errInvalidTimestamp
+}
+
+// resolveHMS completes an HHMMSS timestamp with ref's date, or the previous
+// day when that would put it more than an hour after ref.
+func resolveHMS(ref time.Time, ts string) (time.Time, error) {
+	hour, _ := strconv.Atoi(ts[0:2])
+	minute, _ := strconv.Atoi(ts[2:4])
+	second, _ := strconv.Atoi(ts[4:6])
+	if hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, errInvalidTimestamp
+	}
+	t := time.Date(ref.Year(), ref.Month(), ref.Day(), hour, minute, second, 0, ref.Location())
+	if t.After(ref.Add(time.Hour)) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t, nil
+}
+
 // parseTimeStringIn parses timeStr in the given location and returns a Unix
 // timestamp.
 func parseTimeStringIn(timeStr, layout string, loc *time.Location) (int, error) {
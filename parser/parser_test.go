@@ -1,8 +1,17 @@
 package parser
 
 import (
+	"errors"
+	"flag"
 	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/APRSCN/aprsutils"
 )
 
 // approx reports whether got is within tol of want.
@@ -171,6 +180,16 @@ func TestParseThirdParty(t *testing.T) {
 	if p.SubPacket.From != "OH2RDP-1" {
 		t.Errorf("SubPacket.From = %q, want OH2RDP-1", p.SubPacket.From)
 	}
+
+	// The inner packet is decoded with the outer packet's options.
+	p, err = Parse("SRC>APRS,qAR,N5CAL-1:}OH2RDP-1>BEACON,TCPIP*:!4903.50N/07201.75W_400/004g005t077",
+		WithWarnings(), WithTypedWeather())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.SubPacket == nil || p.SubPacket.WeatherTyped == nil || len(p.SubPacket.Warnings) == 0 {
+		t.Errorf("SubPacket ignored the options: %+v", p.SubPacket)
+	}
 }
 
 func TestParseTelemetryReport(t *testing.T) {
@@ -270,3 +289,1952 @@ func TestPositionlessWeatherMultiField(t *testing.T) {
 		t.Errorf("temperature = %v, want %v", got, (77-32)/1.8)
 	}
 }
+
+func TestParsedStationKey(t *testing.T) {
+	p, err := Parse("n0call-9>APRS,TCPIP*::N0CALL-7 :hello{1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.BaseCall() != "n0call" {
+		t.Errorf("BaseCall() = %q, want n0call", p.BaseCall())
+	}
+	if p.SSID() != "9" {
+		t.Errorf("SSID() = %q, want 9", p.SSID())
+	}
+	if p.StationKey() != "N0CALL" {
+		t.Errorf("StationKey() = %q, want N0CALL", p.StationKey())
+	}
+	if p.AddresseeKey() != p.StationKey() {
+		t.Errorf("AddresseeKey() = %q, want %q", p.AddresseeKey(), p.StationKey())
+	}
+}
+
+func TestParsedObjectKey(t *testing.T) {
+	cases := []struct{ raw, want string }{
+		{"N0CALL>APRS:;ares-eoc *092345z4903.50N/07201.75W-", "ARES-EOC"},
+		{"N0CALL>APRS:;ARES-HQ  *092345z4903.50N/07201.75W-", "ARES-HQ"},
+		{"N0CALL>APRS:)Aid #2!4903.50N/07201.75W-", "AID #2"},
+		{"N0CALL>APRS:>status", ""},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if got := p.ObjectKey(); got != c.want {
+			t.Errorf("%q: ObjectKey() = %q, want %q", c.raw, got, c.want)
+		}
+	}
+}
+
+func TestValidatePacket(t *testing.T) {
+	cases := []struct {
+		name   string
+		packet string
+		want   error
+	}{
+		{"ok", "N0CALL>APRS,WIDE1-1:>status", nil},
+		{"ok with crlf", "N0CALL>APRS:>status\r\n", nil},
+		{"too long for RF", "N0CALL>APRS:>" + strings.Repeat("x", 300), ErrPacketTooLongRF},
+		{"too long for IS", "N0CALL>APRS:>" + strings.Repeat("x", 600), ErrPacketTooLongIS},
+		{"embedded newline", "N0CALL>APRS:>one\r\ntwo", ErrPacketLineBreak},
+		{"control char", "N0CALL>APRS:>bell\x07", ErrPacketControlChar},
+		{"mic-e data bytes", "N0CALL>S32U6T:`(_f\x1c\x1fq>/]", nil},
+		{"mic-e DEL", "N0CALL>S32U6T:`(_f\x7f\x1fq>/]", nil},
+		{"tab", "N0CALL>APRS:>net\ttonight", nil},
+		{"no info", "N0CALL>APRS:", ErrPacketInfoMissing},
+		{"bad header", "N0CALL APRS:>status", ErrPacketHeaderFormat},
+	}
+	for _, c := range cases {
+		err := ValidatePacket(c.packet)
+		if c.want == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", c.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: error = %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestParseCompressedObjectAndItem(t *testing.T) {
+	cases := []struct {
+		raw, name, format string
+		packetType        PacketType
+	}{
+		{"SRC>APRS:;OBJ1     *092345z/5L!!<*e7>7P[Compressed object", "OBJ1", "object", TypeObject},
+		{"SRC>APRS:)AID #2!/5L!!<*e7>7P[Compressed item", "AID #2", "item", TypeItem},
+		// Base-91 '!' bytes right after the flag must not extend the name.
+		{"SRC>APRS:)OBJ!/5L!!!!!!>7P[", "OBJ", "item", TypeItem},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.ObjectName != c.name {
+			t.Errorf("%q: ObjectName = %q, want %q", c.raw, p.ObjectName, c.name)
+		}
+		if p.Format != c.format || p.ObjectFormat != "compressed" {
+			t.Errorf("%q: Format = %q/%q, want %s/compressed", c.raw, p.Format, p.ObjectFormat, c.format)
+		}
+		if !p.PacketType.Has(c.packetType) || !p.HasPosition {
+			t.Errorf("%q: PacketType = %b, HasPosition = %v", c.raw, p.PacketType, p.HasPosition)
+		}
+		if !approx(p.Lat, 49.5, 0.001) {
+			t.Errorf("%q: Lat = %f, want ~49.5", c.raw, p.Lat)
+		}
+	}
+}
+
+func TestTelemetrySeqGap(t *testing.T) {
+	seq := func(raw string) TelemetryData {
+		t.Helper()
+		p, err := Parse("SRC>APRS:T" + raw + ",199,000,255,073,123,01101001")
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", raw, err)
+		}
+		return p.Telemetry
+	}
+
+	prev, next := seq("#998"), seq("#002")
+	if prev.RawSeq != "998" || prev.Seq != 998 {
+		t.Errorf("RawSeq/Seq = %q/%d, want 998/998", prev.RawSeq, prev.Seq)
+	}
+	if gap, ok := next.SeqGap(prev); !ok || gap != 3 {
+		t.Errorf("SeqGap(998 -> 002) = %d, %v; want 3, true", gap, ok)
+	}
+	if gap, ok := seq("#999").SeqGap(prev); !ok || gap != 0 {
+		t.Errorf("SeqGap(998 -> 999) = %d, %v; want 0, true", gap, ok)
+	}
+
+	mic := seq("#MIC")
+	if mic.RawSeq != "MIC" || mic.HasSeq() {
+		t.Errorf("MIC: RawSeq = %q, HasSeq = %v", mic.RawSeq, mic.HasSeq())
+	}
+	if len(mic.Vals) != 5 {
+		t.Errorf("MIC: Vals = %v, want 5 values", mic.Vals)
+	}
+	if _, ok := mic.SeqGap(prev); ok {
+		t.Error("SeqGap with a MIC report should not be ok")
+	}
+
+	// Comment telemetry counts in base 91 and wraps at 8281, not 1000.
+	comment := func(s string) TelemetryData {
+		t.Helper()
+		p, err := Parse("SRC>APRS:!4903.50N/07201.75W>|" + s + "!!!!!!!!!!|")
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", s, err)
+		}
+		return p.Telemetry
+	}
+	last, first := comment("{{"), comment("!\"")
+	if !last.Base91 || last.Seq != 8280 || first.Seq != 1 {
+		t.Fatalf("comment Seq = %d/%d (Base91 %v), want 8280/1", last.Seq, first.Seq, last.Base91)
+	}
+	if gap, ok := first.SeqGap(last); !ok || gap != 1 {
+		t.Errorf("SeqGap(8280 -> 1) = %d, %v; want 1, true", gap, ok)
+	}
+	if _, ok := first.SeqGap(prev); ok {
+		t.Error("SeqGap across T# and comment telemetry should not be ok")
+	}
+	if (TelemetryData{RawSeq: "A1B"}).HasSeq() {
+		t.Error("HasSeq should be false for a non-numeric T# token")
+	}
+}
+
+func TestParsedUnverified(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want bool
+	}{
+		{"N0CALL>APRS,TCPXX*,qAX,T2TEST:>unverified", true},
+		{"N0CALL>APRS,TCPXX*:>unverified, no q", true},
+		{"N0CALL>APRS,qAX,T2TEST:>qAX only", true},
+		{"N0CALL>APRS,TCPIP*,qAC,T2TEST:>verified", false},
+		{"N0CALL>APRS,WIDE1-1,qAR,IGATE:>from RF", false},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if got := p.Unverified(); got != c.want {
+			t.Errorf("%q: Unverified() = %v, want %v", c.raw, got, c.want)
+		}
+	}
+	p, _ := Parse("N0CALL>APRS,TCPXX*,qAX,T2TEST:>unverified")
+	if q, via := p.QConstruct(); q != "qAX" || via != "T2TEST" {
+		t.Errorf("QConstruct() = %q, %q; want qAX, T2TEST", q, via)
+	}
+}
+
+func TestParseRegexpStats(t *testing.T) {
+	cache := aprsutils.CompiledRegexps
+	cache.EnableStats(true)
+	defer cache.EnableStats(false)
+	cache.ResetStats()
+
+	if _, err := Parse("N0CALL>APRS:!4903.50N/07201.75W-Test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const uncompressed = `^[0-9\s]{4}\.[0-9\s]{2}[NS].[0-9\s]{5}\.[0-9\s]{2}[EW]`
+	if s := cache.Stats()[uncompressed]; s.Consulted != 1 || s.Matched != 1 {
+		t.Errorf("uncompressed position pattern stats = %+v, want 1/1", s)
+	}
+}
+
+func TestParseDirectedQuery(t *testing.T) {
+	p, err := Parse("K1ABC-7>APRS::N0CALL   :?APRSP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Query != "APRSP" || p.Addressee != "N0CALL" {
+		t.Errorf("Query/Addressee = %q/%q, want APRSP/N0CALL", p.Query, p.Addressee)
+	}
+	p, _ = Parse("K1ABC-7>APRS::N0CALL   :hello?")
+	if p.Query != "" {
+		t.Errorf("plain message parsed as query %q", p.Query)
+	}
+}
+
+func TestParseNoBodyErrors(t *testing.T) {
+	p, err := Parse("N0CALL>APRS,WIDE1-1")
+	if !errors.Is(err, ErrNoBody) {
+		t.Errorf("colon-less line: error = %v, want ErrNoBody", err)
+	}
+	if p.From != "N0CALL" || p.To != "APRS" {
+		t.Errorf("colon-less line: header not decoded (From %q, To %q)", p.From, p.To)
+	}
+
+	p, err = Parse("N0CALL>APRS,WIDE1-1:")
+	if !errors.Is(err, ErrEmptyBody) {
+		t.Errorf("empty body: error = %v, want ErrEmptyBody", err)
+	}
+	if p.From != "N0CALL" || len(p.Path) != 1 {
+		t.Errorf("empty body: header not decoded (From %q, Path %v)", p.From, p.Path)
+	}
+
+	if _, err = Parse(":>status"); !errors.Is(err, ErrEmptyHead) {
+		t.Errorf("empty head: error = %v, want ErrEmptyHead", err)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	blob := "N0CALL>APRS:>first\r\n" +
+		"BROKEN LINE\r\n" +
+		"\r\n" +
+		"K1ABC>APRS:>third\n"
+	results, errs := ParseAll(blob)
+	if len(results) != 3 || len(errs) != 3 {
+		t.Fatalf("got %d results / %d errors, want 3/3", len(results), len(errs))
+	}
+	if errs[0] != nil || results[0].Status != "first" {
+		t.Errorf("packet 0: err %v, status %q", errs[0], results[0].Status)
+	}
+	if errs[1] == nil {
+		t.Error("packet 1: expected an error for the malformed line")
+	}
+	if errs[2] != nil || results[2].From != "K1ABC" {
+		t.Errorf("packet 2: err %v, from %q", errs[2], results[2].From)
+	}
+}
+
+func TestPathQuality(t *testing.T) {
+	cases := []struct {
+		raw       string
+		injection Injection
+		hops      int
+		score     float64
+	}{
+		{"N0CALL>APRS,qAR,IGATE:>direct RF", InjectionRF, 0, 1},
+		{"N0CALL>APRS,DIGI1,WIDE1*,WIDE2-1,qAR,IGATE:>one hop", InjectionRF, 1, 0.5},
+		{"N0CALL>APRS,DIGI1,DIGI2*,qAR,IGATE:>two hops", InjectionRF, 2, 1.0 / 3},
+		{"N0CALL>APRS,WIDE1-1,WIDE2-1,qAR,IGATE:>heard directly", InjectionRF, 0, 1},
+		{"N0CALL>APRS,WIDE2-1,qAR,IGATE:>as sent", InjectionRF, 0, 1},
+		{"N0CALL>APRS,WIDE2*,qAR,IGATE:>two untraced hops", InjectionRF, 2, 1.0 / 3},
+		{"N0CALL>APRS,WIDE3-1*,qAR,IGATE:>two untraced hops", InjectionRF, 2, 1.0 / 3},
+		{"N0CALL>APRS,DIGI1*,qAO,IGATE:>relayed", InjectionRelayed, 1, 0.25},
+		{"N0CALL>APRS,DIGI1*,qAS,SERVER:>server", InjectionServer, 1, 0.5},
+		{"N0CALL>APRS,TCPIP*,qAC,SERVER:>client", InjectionClient, 0, 1},
+		{"N0CALL>APRS,WIDE1-1:>no q", InjectionUnknown, 0, 0},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		info := PathQuality(p)
+		if info.Injection != c.injection || info.Hops != c.hops || !approx(info.Score, c.score, 1e-9) {
+			t.Errorf("%q: got %+v, want injection %q hops %d score %v", c.raw, info, c.injection, c.hops, c.score)
+		}
+	}
+}
+
+func TestParseTypedWeather(t *testing.T) {
+	raw := "SRC>APRS,qAR,N5CAL-1:_12345678c220s004g005t077r001p002P003h50b10130"
+	p, err := Parse(raw, WithTypedWeather())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := p.WeatherTyped
+	if w == nil {
+		t.Fatal("WeatherTyped is nil")
+	}
+	checks := []struct {
+		key   string
+		field WeatherField
+		got   float64
+	}{
+		{"windDirection", WeatherWindDirection, w.WindDirectionDeg},
+		{"windSpeed", WeatherWindSpeed, w.WindSpeedMs},
+		{"windGust", WeatherWindGust, w.WindGustMs},
+		{"temperature", WeatherTemperature, w.TemperatureC},
+		{"rain1h", WeatherRain1h, w.Rain1hMm},
+		{"rain24h", WeatherRain24h, w.Rain24hMm},
+		{"rainSinceMidnight", WeatherRainSinceMidnight, w.RainSinceMidnightMm},
+		{"humidity", WeatherHumidity, w.Humidity},
+		{"pressure", WeatherPressure, w.PressureHpa},
+	}
+	for _, c := range checks {
+		want, ok := p.Weather[c.key]
+		if !ok {
+			t.Errorf("map missing %q", c.key)
+			continue
+		}
+		if !w.Has(c.field) || c.got != want {
+			t.Errorf("%s: typed = %v (present %v), map = %v", c.key, c.got, w.Has(c.field), want)
+		}
+	}
+	if w.Has(WeatherSnow) || w.Has(WeatherLuminosity) {
+		t.Errorf("absent fields reported present: %b", w.Present)
+	}
+
+	// Opt-in only.
+	if p, _ := Parse(raw); p.WeatherTyped != nil {
+		t.Error("WeatherTyped populated without WithTypedWeather")
+	}
+}
+
+func TestEncodePositionDataType(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 23, 45, 0, 0, time.UTC)
+	cases := []struct {
+		messageCapable bool
+		timestamp      *time.Time
+		want           string
+	}{
+		{false, nil, "!"},
+		{true, nil, "="},
+		{false, &ts, "/"},
+		{true, &ts, "@"},
+	}
+	for _, c := range cases {
+		info, err := EncodePosition(PositionReport{
+			Lat: 49.058333, Lon: -72.029167, SymbolTable: "/", Symbol: "-",
+			Comment: "Test", MessageCapable: c.messageCapable, Timestamp: c.timestamp,
+		})
+		if err != nil {
+			t.Fatalf("EncodePosition: %v", err)
+		}
+		if info[:1] != c.want {
+			t.Errorf("data type = %q, want %q (info %q)", info[:1], c.want, info)
+		}
+
+		p, err := Parse("N0CALL>APRS:" + info)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", info, err)
+		}
+		if p.MessageCapable != c.messageCapable {
+			t.Errorf("%q: MessageCapable = %v, want %v", info, p.MessageCapable, c.messageCapable)
+		}
+		if (p.RawTimestamp != "") != (c.timestamp != nil) {
+			t.Errorf("%q: RawTimestamp = %q", info, p.RawTimestamp)
+		}
+		if !approx(p.Lat, 49.058333, 0.0002) || !approx(p.Lon, -72.029167, 0.0002) || p.Comment != "Test" {
+			t.Errorf("%q: round trip gave %f, %f, %q", info, p.Lat, p.Lon, p.Comment)
+		}
+	}
+
+	if info, _ := EncodePosition(PositionReport{Lat: 10.99999, Lon: 0, SymbolTable: "/", Symbol: ">"}); info != "!1100.00N/00000.00E>" {
+		t.Errorf("minute carry: got %q", info)
+	}
+}
+
+func TestParseCommentLimit(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	raw := "N0CALL>APRS:!4903.50N/07201.75W-" + long
+
+	p, err := Parse(raw, WithCommentLimit(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Comment) != MaxCommentLength || !p.Truncated {
+		t.Errorf("default cap: len %d truncated %v, want %d true", len(p.Comment), p.Truncated, MaxCommentLength)
+	}
+	if !approx(p.Lat, 49.0583, 0.001) {
+		t.Errorf("cap affected position: Lat = %f", p.Lat)
+	}
+
+	p, _ = Parse(raw, WithCommentLimit(10))
+	if p.Comment != "xxxxxxxxxx" || !p.Truncated {
+		t.Errorf("custom cap: %q truncated %v", p.Comment, p.Truncated)
+	}
+
+	p, _ = Parse("N0CALL>APRS:!4903.50N/07201.75W-short", WithCommentLimit(10))
+	if p.Comment != "short" || p.Truncated {
+		t.Errorf("short comment: %q truncated %v", p.Comment, p.Truncated)
+	}
+
+	if p, _ = Parse(raw); p.Truncated || len(p.Comment) != 100 {
+		t.Error("comment capped without WithCommentLimit")
+	}
+}
+
+func TestParsedFromRF(t *testing.T) {
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"WIDE1-1,qAR,IGATE", true},
+		{"qAr,IGATE", true},
+		{"DIGI*,qAo,IGATE", true},
+		{"qAO,IGATE", true},
+		{"DIGI*,qAS,PEER", true},
+		{"TCPIP*,qAS,PEER", false},
+		{"TCPIP*,qAC,SERVER", false},
+		{"TCPXX*,qAX,SERVER", false},
+		{"qAU,SERVER", false},
+		{"WIDE1-1", false},
+	}
+	for _, c := range cases {
+		raw := "N0CALL>APRS," + c.path + ":>test"
+		p, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", raw, err)
+		}
+		if got := p.FromRF(); got != c.want {
+			t.Errorf("%s: FromRF() = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestParseStatusTimestamp(t *testing.T) {
+	p, err := Parse("N0CALL>APRS:>092345zNet tonight")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.RawTimestamp != "092345z" || p.Timestamp == 0 {
+		t.Errorf("RawTimestamp/Timestamp = %q/%d, want 092345z/non-zero", p.RawTimestamp, p.Timestamp)
+	}
+	if p.Status != "Net tonight" {
+		t.Errorf("Status = %q, want %q", p.Status, "Net tonight")
+	}
+
+	p, _ = Parse("N0CALL>APRS:>123456 is not a timestamp")
+	if p.RawTimestamp != "" || p.Status != "123456 is not a timestamp" {
+		t.Errorf("plain status: RawTimestamp %q, Status %q", p.RawTimestamp, p.Status)
+	}
+}
+
+func TestParsedReset(t *testing.T) {
+	p, err := Parse("SRC>APRS,qAR,N5CAL-1:}OH2RDP-1>BEACON,TCPIP*:!4903.50N/07201.75W_220/004g005t077")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.SubPacket == nil || len(p.SubPacket.Weather) == 0 {
+		t.Fatal("test packet did not populate SubPacket weather")
+	}
+	p.Reset()
+	if !reflect.DeepEqual(p, Parsed{}) {
+		t.Errorf("reset Parsed differs from a fresh one: %+v", p)
+	}
+}
+
+// loadCorpus reads a packet corpus from testdata (corpus.txt by default).
+func loadCorpus(tb testing.TB, name ...string) []string {
+	tb.Helper()
+	file := "corpus.txt"
+	if len(name) > 0 {
+		file = name[0]
+	}
+	f, err := os.Open("testdata/" + file)
+	if err != nil {
+		tb.Fatalf("open corpus: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	packets, err := ReadCorpus(f)
+	if err != nil {
+		tb.Fatalf("read corpus: %v", err)
+	}
+	return packets
+}
+
+func TestCorpusParses(t *testing.T) {
+	for _, raw := range loadCorpus(t) {
+		if _, err := Parse(raw); err != nil {
+			t.Errorf("Parse(%q): %v", raw, err)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	packets := loadCorpus(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Parse(packets[i%len(packets)])
+	}
+}
+
+// BenchmarkParsePosition covers the bulk of a fullfeed: plain position
+// reports with a short comment and no extensions.
+func BenchmarkParsePosition(b *testing.B) {
+	packets := []string{
+		"N0CALL-9>APRS,WIDE1-1,qAR,IGATE:!4903.50N/07201.75W>Mobile 144.390",
+		"N0CALL>APRS,TCPIP*,qAC,T2TEST:=4903.50N/07201.75W-Home station",
+		"N0CALL>APRS,TCPIP*,qAC,T2TEST:!/5L!!<*e7>7P[Compressed tracker",
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Parse(packets[i%len(packets)])
+	}
+}
+
+// BenchmarkParseEnabledFormats parses the corpus with only positions enabled;
+// compare with BenchmarkParse.
+func BenchmarkParseEnabledFormats(b *testing.B) {
+	packets := loadCorpus(b)
+	opt := WithEnabledFormats("position", "mic-e")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Parse(packets[i%len(packets)], opt)
+	}
+}
+
+func TestWithEnabledFormats(t *testing.T) {
+	opt := WithEnabledFormats("position")
+
+	cases := []struct {
+		raw, format string
+	}{
+		{"N0CALL>APRS::WU2Z     :Testing{003", "message"},
+		{"N0CALL>APRS:T#005,199,000,255,073,123,01101001", "telemetry"},
+		{"N0CALL>APRS:_10090556c220s004g005t077", "weather"},
+		{"N0CALL>APRS:}N1CALL>APRS,TCPIP,N0CALL*:>hi", "thirdparty"},
+		{"OX8AAA>T7UU97:`(T4l!u>/]", "mic-e"},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw, opt)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.Format != c.format || p.PacketType != 0 || p.From == "" {
+			t.Errorf("%q: Format = %q, PacketType = %b, From = %q; want skipped %q", c.raw, p.Format, p.PacketType, p.From, c.format)
+		}
+		if p.MessageText != "" || p.Telemetry.Vals != nil || p.Weather != nil || p.SubPacket != nil || p.HasPosition {
+			t.Errorf("%q: disabled type was decoded: %+v", c.raw, p)
+		}
+	}
+
+	for _, raw := range []string{
+		"N0CALL>APRS:!4903.50N/07201.75W>Mobile",
+		"N0CALL>APRS:Hello! !4903.50N/07201.75W>Mobile",
+	} {
+		p, err := Parse(raw, opt)
+		if err != nil || !p.HasPosition || p.Format != "uncompressed" {
+			t.Errorf("%q: enabled position not decoded: %q, %v", raw, p.Format, err)
+		}
+	}
+}
+
+func TestParseCompressedAltitude(t *testing.T) {
+	cases := []struct {
+		cs     string
+		want   float64
+		source AltitudeSource
+	}{
+		{"S]Q", 10004 * 0.3048, AltitudeCompressed},  // ~3 km
+		{"bKQ", 147637 * 0.3048, AltitudeCompressed}, // ~45 km balloon
+		{"{{Q", 0, AltitudeNone},                     // corrupt: far above any balloon
+	}
+	for _, c := range cases {
+		raw := "N0CALL>APRS:!/5L!!<*e7O" + c.cs
+		p, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", raw, err)
+		}
+		if p.AltitudeSource != c.source || !approx(p.Altitude, c.want, c.want*0.002) {
+			t.Errorf("cs %q: Altitude = %f (%q), want ~%f (%q)", c.cs, p.Altitude, p.AltitudeSource, c.want, c.source)
+		}
+	}
+
+	p, _ := Parse("N0CALL>APRS:!4903.50N/07201.75W>/A=001234")
+	if p.AltitudeSource != AltitudeComment {
+		t.Errorf("comment altitude source = %q, want comment", p.AltitudeSource)
+	}
+}
+
+func TestAltitudePrecedence(t *testing.T) {
+	cases := []struct {
+		raw     string
+		want    float64
+		source  AltitudeSource
+		comment string
+	}{
+		// Compressed altitude beats a comment /A=.
+		{"N0CALL>APRS:!/5L!!<*e7OS]Q/A=001234 hi", 10004 * 0.3048, AltitudeCompressed, "hi"},
+		// Mic-E base-91 altitude beats a comment /A=.
+		{"OX8AAA>T7UU97:`(T4l!u>/]\"83}/A=001234 hi", 392, AltitudeMicE, "hi"},
+		// Mic-E without its own altitude falls back to the comment.
+		{"OX8AAA>T7UU97:`(T4l!u>/]/A=001234 hi", 1234 * 0.3048, AltitudeComment, "hi"},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.AltitudeSource != c.source || !approx(p.Altitude, c.want, c.want*0.002) {
+			t.Errorf("%q: Altitude = %f (%q), want ~%f (%q)", c.raw, p.Altitude, p.AltitudeSource, c.want, c.source)
+		}
+		if !strings.Contains(p.Comment, c.comment) || strings.Contains(p.Comment, "/A=") {
+			t.Errorf("%q: Comment = %q", c.raw, p.Comment)
+		}
+	}
+}
+
+func TestMicECommentTelemetryAndAltitude(t *testing.T) {
+	// "|#$%&|" is sequence 185 with a first value of 369; "\"83}" is 392 m.
+	for _, raw := range []string{
+		"OX8AAA>T7UU97:`(T4l!u>/]\"83}|#$%&|LoRa",
+		"OX8AAA>T7UU97:`(T4l!u>/]|#$%&|\"83}LoRa",
+		"OX8AAA>T7UU97:`(T4l!u>/]|#$%&|LoRa\"83}",
+	} {
+		p, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", raw, err)
+		}
+		if p.AltitudeSource != AltitudeMicE || !approx(p.Altitude, 392, 1) {
+			t.Errorf("%q: Altitude = %f (%q)", raw, p.Altitude, p.AltitudeSource)
+		}
+		if p.Telemetry.Seq != 185 || len(p.Telemetry.Vals) == 0 || p.Telemetry.Vals[0] != 369 {
+			t.Errorf("%q: Telemetry = %+v", raw, p.Telemetry)
+		}
+		if p.Comment != "]LoRa" {
+			t.Errorf("%q: Comment = %q, want %q", raw, p.Comment, "]LoRa")
+		}
+	}
+
+	// Only the first "xxx}" is the altitude.
+	p, err := Parse("OX8AAA>T7UU97:`(T4l!u>/]\"83}LoRa {ab}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(p.Altitude, 392, 1) || p.Comment != "]LoRa {ab}" {
+		t.Errorf("later \"}\": Altitude = %f, Comment = %q", p.Altitude, p.Comment)
+	}
+}
+
+func TestWithCoordinatePrecision(t *testing.T) {
+	const (
+		uncompressed = "N0CALL>APRS:!4930.00N/07245.00W>"
+		compressed   = "N0CALL>APRS:!/5L!!<*e7>7P["
+	)
+	u, err := Parse(uncompressed, WithCoordinatePrecision(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Parse(compressed, WithCoordinatePrecision(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Lat != c.Lat || u.Lon != c.Lon {
+		t.Errorf("rounded positions differ: uncompressed %v,%v compressed %v,%v", u.Lat, u.Lon, c.Lat, c.Lon)
+	}
+	if u.Lat != 49.5 || u.Lon != -72.75 {
+		t.Errorf("rounded position = %v,%v, want 49.5,-72.75", u.Lat, u.Lon)
+	}
+
+	// Default: no rounding, so the compressed decode keeps its noise.
+	raw, _ := Parse(compressed)
+	if raw.Lon == u.Lon {
+		t.Errorf("unrounded compressed Lon = %v, expected decode noise", raw.Lon)
+	}
+}
+
+func TestNormalizeLatLon(t *testing.T) {
+	lonCases := []struct{ in, want float64 }{
+		{180, 180},
+		{-180, 180},
+		{180.04, -179.96},
+		{-180.5, 179.5},
+		{-72.75, -72.75},
+		{540, 180},
+	}
+	for _, c := range lonCases {
+		if got := normalizeLon(c.in); !approx(got, c.want, 1e-9) {
+			t.Errorf("normalizeLon(%v) = %v, want %v", c.in, got, c.want)
+		}
+	}
+	latCases := []struct{ in, want float64 }{
+		{90, 90},
+		{-90, -90},
+		{90.02, 90},
+		{-90.02, -90},
+		{49.5, 49.5},
+	}
+	for _, c := range latCases {
+		if got := normalizeLat(c.in); got != c.want {
+			t.Errorf("normalizeLat(%v) = %v, want %v", c.in, got, c.want)
+		}
+	}
+
+	// Compressed extremes: "!!!!" decodes to exactly 90N / 180W, "{{{{" to
+	// just past 90S / 180E.
+	cases := []struct {
+		raw      string
+		lat, lon float64
+	}{
+		{"N0CALL>APRS:!/!!!!!!!!>  !", 90, 180},
+		{"N0CALL>APRS:!/{{{{{{{{>  !", -90, -179.9565},
+		{"N0CALL>APRS:!8959.99N/17959.99E>", 89.99983, 179.99983},
+		{"N0CALL>APRS:!8959.99S/17959.99W>", -89.99983, -179.99983},
+		// Exactly the poles and the antimeridian.
+		{"N0CALL>APRS:!9000.00N/18000.00W>", 90, 180},
+		{"N0CALL>APRS:!9000.00S/18000.00E>", -90, 180},
+		{"N0CALL>APRS:!9000.0 N/18000.0 E>", 90, 180},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if !approx(p.Lat, c.lat, 1e-4) || !approx(p.Lon, c.lon, 1e-4) {
+			t.Errorf("%q: position = %v,%v, want %v,%v", c.raw, p.Lat, p.Lon, c.lat, c.lon)
+		}
+		if !p.HasPosition {
+			t.Errorf("%q: HasPosition = false", c.raw)
+		}
+	}
+
+	// Past them is still out of range.
+	for _, raw := range []string{
+		"N0CALL>APRS:!9000.01N/07201.75W>",
+		"N0CALL>APRS:!4903.50S/18000.01E>",
+		"N0CALL>APRS:!9100.00N/07201.75W>",
+		"N0CALL>APRS:!4903.50N/18100.00W>",
+	} {
+		if _, err := Parse(raw); err == nil {
+			t.Errorf("Parse(%q) accepted an out-of-range position", raw)
+		}
+	}
+}
+
+func TestParseBulletinKind(t *testing.T) {
+	cases := []struct {
+		raw   string
+		kind  BulletinKind
+		group string
+		text  string
+	}{
+		{"N0CALL>APRS::BLN1     :Net tonight 20:00", BulletinKindGeneral, "", "Net tonight 20:00"},
+		{"N0CALL>APRS::BLN1WX   :Storm warning", BulletinKindGroup, "WX", "Storm warning"},
+		{"N0CALL>APRS::BLNA     :Hamfest Saturday", BulletinKindAnnouncement, "", "Hamfest Saturday"},
+		{"N0CALL>APRS::N1CALL   :hello", BulletinKindNone, "", "hello"},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.BulletinKind != c.kind || p.BulletinGroup != c.group || p.MessageText != c.text {
+			t.Errorf("%q: kind=%q group=%q text=%q, want %q %q %q",
+				c.raw, p.BulletinKind, p.BulletinGroup, p.MessageText, c.kind, c.group, c.text)
+		}
+	}
+}
+
+func TestParseHeaderOnly(t *testing.T) {
+	packets := []string{
+		"N0CALL-9>APRS,WIDE1-1,WIDE2-2,qAR,IGATE:!4903.50N/07201.75W>",
+		"N0CALL>APZ001,TCPIP*,qAC,T2TEST::N1CALL   :hello{01",
+		"N0CALL>APRS",
+	}
+	for _, raw := range packets {
+		from, to, path, err := ParseHeader(raw)
+		if err != nil {
+			t.Fatalf("ParseHeader(%q): %v", raw, err)
+		}
+		p, _ := Parse(raw)
+		if from != p.From || to != p.To || !reflect.DeepEqual(path, p.Path) {
+			t.Errorf("ParseHeader(%q) = %q %q %v, Parse gave %q %q %v", raw, from, to, path, p.From, p.To, p.Path)
+		}
+	}
+
+	if _, _, _, err := ParseHeader("N0CALL>bad call:>x"); err == nil {
+		t.Error("invalid tocall accepted")
+	}
+	if _, to, _, err := ParseHeader("N0CALL>bad call:>x", WithDisableToCallsignValidate()); err != nil || to != "bad call" {
+		t.Errorf("lenient ParseHeader: to=%q err=%v", to, err)
+	}
+	if _, _, _, err := ParseHeader(":>x"); !errors.Is(err, ErrEmptyHead) {
+		t.Errorf("empty head: err = %v, want ErrEmptyHead", err)
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata golden files")
+
+// TestFormatLogGolden renders testdata/formatlog.input and compares the result
+// with testdata/formatlog.golden (regenerate with go test -run FormatLog -update).
+func TestFormatLogGolden(t *testing.T) {
+	var b strings.Builder
+	for _, raw := range loadCorpus(t, "formatlog.input") {
+		p, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", raw, err)
+		}
+		b.WriteString(FormatLog(p))
+		b.WriteString("\n")
+	}
+
+	const golden = "testdata/formatlog.golden"
+	if *updateGolden {
+		if err := os.WriteFile(golden, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != string(want) {
+		t.Errorf("FormatLog output differs from %s:\ngot:\n%s\nwant:\n%s", golden, got, want)
+	}
+
+	p, _ := Parse("N0CALL>APRS:>bell\x07 text")
+	p.Timestamp = 1700000000
+	if got, want := FormatLog(p), "2023-11-14T22:13:20Z N0CALL>APRS status - - bell  text"; got != want {
+		t.Errorf("FormatLog = %q, want %q", got, want)
+	}
+}
+
+func TestControlChars(t *testing.T) {
+	const raw = "N0CALL>APRS:!4903.50N/07201.75W>Hello\x00 there\x07"
+
+	p, _ := Parse(raw)
+	if !p.ControlChars {
+		t.Error("ControlChars = false for a packet with NUL and BEL")
+	}
+
+	p, err := Parse(raw, WithStripControlChars())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !p.ControlChars || p.Comment != "Hello there" || p.Raw != raw {
+		t.Errorf("stripped: ControlChars=%v Comment=%q Raw=%q", p.ControlChars, p.Comment, p.Raw)
+	}
+	if !approx(p.Lat, 49.0583, 1e-3) || !approx(p.Lon, -72.0292, 1e-3) {
+		t.Errorf("stripped position = %v,%v", p.Lat, p.Lon)
+	}
+
+	// A NUL inside the position must not break decoding once stripped.
+	p, err = Parse("N0CALL>APRS:!4903.50N/\x0007201.75W>", WithStripControlChars())
+	if err != nil || !p.HasPosition {
+		t.Errorf("NUL in position: err=%v HasPosition=%v", err, p.HasPosition)
+	}
+
+	// A TAB is text, not a stray control character.
+	if p, _ := Parse("N0CALL>APRS:>Net\ttonight", WithStripControlChars()); p.ControlChars || p.Status != "Net\ttonight" {
+		t.Errorf("TAB: ControlChars=%v Status=%q", p.ControlChars, p.Status)
+	}
+
+	// Mic-E data bytes in 0x1c-0x1f are not stray.
+	if p, _ := Parse("N0CALL>S32U6T:`(_f\x1c\x1fq>/]"); p.ControlChars {
+		t.Error("Mic-E bytes flagged as control characters")
+	}
+}
+
+// TestCommentExtensionsStillParse guards the prefix checks in parseComment:
+// every extension must still be decoded when its marker is present.
+func TestCommentExtensionsStillParse(t *testing.T) {
+	p, err := Parse("N0CALL>APRS:!4903.50N/07201.75W>088/036/270/729/A=001234|!!!!!!!!!!|!W53!hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Course != 88 || !approx(p.Speed, 36*1.852, 1e-9) || p.Bearing != 270 || p.NRQ != 729 {
+		t.Errorf("course/speed/DF = %v %v %v %v", p.Course, p.Speed, p.Bearing, p.NRQ)
+	}
+	if p.AltitudeSource != AltitudeComment || !approx(p.Altitude, 1234*0.3048, 1e-9) {
+		t.Errorf("altitude = %v (%q)", p.Altitude, p.AltitudeSource)
+	}
+	if p.Telemetry.RawSeq != "!!" {
+		t.Errorf("telemetry = %+v", p.Telemetry)
+	}
+	if p.DAODatumByte != "W" {
+		t.Errorf("DAO datum = %q, want W", p.DAODatumByte)
+	}
+
+	p, _ = Parse("N0CALL>APRS:!4903.50N/07201.75W#PHG5132/Digi")
+	if p.PHG != "5132" || p.PHGDir != "90" || p.Comment != "Digi" {
+		t.Errorf("PHG = %q dir %q comment %q", p.PHG, p.PHGDir, p.Comment)
+	}
+	p, _ = Parse("N0CALL>APRS:!4903.50N/07201.75W#RNG0050 range")
+	if !approx(p.RNG, 50*1.609344, 1e-9) || p.Comment != "range" {
+		t.Errorf("RNG = %v comment %q", p.RNG, p.Comment)
+	}
+	// A single '!' or '|' must be left in the comment.
+	p, _ = Parse("N0CALL>APRS:!4903.50N/07201.75W>Hi! a|b")
+	if p.Comment != "Hi! a|b" {
+		t.Errorf("comment = %q", p.Comment)
+	}
+}
+
+func TestRawCourseSpeed(t *testing.T) {
+	cases := []struct {
+		raw   string
+		cs    string
+		fixed bool
+	}{
+		{"N0CALL>APRS:!4903.50N/07201.75W-000/000Home", "000/000", true},
+		{"N0CALL>APRS:!4903.50N/07201.75W>088/036Mobile", "088/036", false},
+		{"N0CALL>APRS:!4903.50N/07201.75W-Home", "", false},
+		// For weather, CSE/SPD is the wind: calm is not "fixed".
+		{"N0CALL>APRS:!4903.50N/07201.75W_000/000g000t050", "000/000", false},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.RawCourseSpeed != c.cs || p.IsFixed != c.fixed {
+			t.Errorf("%q: RawCourseSpeed=%q IsFixed=%v, want %q %v", c.raw, p.RawCourseSpeed, p.IsFixed, c.cs, c.fixed)
+		}
+	}
+}
+
+func TestDAOForms(t *testing.T) {
+	base, _ := Parse("N0CALL>APRS:!4903.50N/07201.75W>")
+
+	// W-form: +0.005' lat, +0.003' lon (away from zero).
+	w, _ := Parse("N0CALL>APRS:!4903.50N/07201.75W>!W53!")
+	// w-form: 'O' = 46 -> 46/91 * 0.01' ~ 0.00505', '=' = 28 -> ~0.00308'.
+	b91, _ := Parse("N0CALL>APRS:!4903.50N/07201.75W>!wO=!")
+
+	const minute = 1.0 / 60
+	if !approx(w.Lat-base.Lat, 0.005*minute, 1e-9) || !approx(base.Lon-w.Lon, 0.003*minute, 1e-9) {
+		t.Errorf("W-form offsets = %v, %v", w.Lat-base.Lat, base.Lon-w.Lon)
+	}
+	// The two forms agree to within the W-form resolution (0.001').
+	if !approx(w.Lat, b91.Lat, 0.001*minute) || !approx(w.Lon, b91.Lon, 0.001*minute) {
+		t.Errorf("W-form %v,%v and w-form %v,%v differ", w.Lat, w.Lon, b91.Lat, b91.Lon)
+	}
+	for _, p := range []Parsed{w, b91} {
+		if p.DAODatumByte != "W" || p.DAODatum != "WGS84" {
+			t.Errorf("datum = %q (%q), want W (WGS84)", p.DAODatumByte, p.DAODatum)
+		}
+	}
+
+	// An unassigned datum letter still refines the position, but has no name.
+	x, _ := Parse("N0CALL>APRS:!4903.50N/07201.75W>!X53!")
+	if x.DAODatumByte != "X" || x.DAODatum != "" || !approx(x.Lat, w.Lat, 1e-12) {
+		t.Errorf("X datum: byte %q name %q lat %v", x.DAODatumByte, x.DAODatum, x.Lat)
+	}
+}
+
+func TestWithWarnings(t *testing.T) {
+	// Humidity is two digits; a third one ends the weather data.
+	const raw = "N0CALL>APRS:!4903.50N/07201.75W_220/004g005t077h150b10130"
+
+	p, err := Parse(raw, WithWarnings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Weather["humidity"] != 15 {
+		t.Errorf("humidity = %v, want 15", p.Weather["humidity"])
+	}
+	want := []Warning{{Field: "humidity", Message: "humidity 150 has three digits, read as 15"}}
+	if !reflect.DeepEqual(p.Warnings, want) {
+		t.Errorf("Warnings = %+v, want %+v", p.Warnings, want)
+	}
+
+	// Without the option warnings are discarded, and the data is the same.
+	if q, _ := Parse(raw); q.Warnings != nil || !reflect.DeepEqual(q.Weather, p.Weather) {
+		t.Errorf("without WithWarnings: Warnings = %+v, Weather = %v", q.Warnings, q.Weather)
+	}
+
+	// h00 is 100%, and fields after a two-digit humidity are still parsed.
+	p, _ = Parse("N0CALL>APRS:!4903.50N/07201.75W_220/004g005t077h00b10130", WithWarnings())
+	if p.Weather["humidity"] != 100 || p.Weather["pressure"] != 1013 || p.Warnings != nil {
+		t.Errorf("h00: weather = %v, warnings = %+v", p.Weather, p.Warnings)
+	}
+
+	// Out-of-range values are kept as sent, with a warning.
+	p, _ = Parse("N0CALL>APRS:!4903.50N/07201.75W_400/004g005t077h50b10130", WithWarnings())
+	if p.Weather["windDirection"] != 400 || len(p.Warnings) != 1 || p.Warnings[0].Field != "windDirection" {
+		t.Errorf("wind direction 400: weather = %v, warnings = %+v", p.Weather, p.Warnings)
+	}
+
+	cases := []struct {
+		raw   string
+		field string
+	}{
+		{"N0CALL>APRS:!4903.50N/07201.75W>400/010", "course"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>1 3/010", "course"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>090/0 1", "speed"},
+		{"N0CALL>APRS:!4903.50N/07201.75W#PHG5139", "phg"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>!Wxy!", "dao"},
+	}
+	for _, c := range cases {
+		p, _ := Parse(c.raw, WithWarnings())
+		if len(p.Warnings) != 1 || p.Warnings[0].Field != c.field {
+			t.Errorf("%q: Warnings = %+v, want one %s warning", c.raw, p.Warnings, c.field)
+		}
+	}
+}
+
+func TestMatchAck(t *testing.T) {
+	sent := SentMessage{From: "N0CALL-7", Addressee: "N1CALL", MsgNo: "AB"}
+	cases := []struct {
+		raw  string
+		want bool
+	}{
+		{"N1CALL>APRS::N0CALL-7 :ackAB", true},           // standalone ack
+		{"N1CALL>APRS::N0CALL-7 :ackAB}", true},          // reply-ack form, nothing to ack back
+		{"N1CALL>APRS::N0CALL-7 :ackXY}AB", true},        // reply-ack form carrying our number
+		{"n1call>APRS::n0call-7 :Roger{XY}AB", true},     // reply-ack on an answering message
+		{"N1CALL>APRS::N0CALL-7 :rejAB", false},          // rejected, not delivered
+		{"N1CALL>APRS::N0CALL-7 :ackAC", false},          // other message
+		{"N2CALL>APRS::N0CALL-7 :ackAB", false},          // wrong station
+		{"N1CALL>APRS::N0CALL-8 :ackAB", false},          // addressed elsewhere
+		{"N1CALL>APRS::N0CALL-7 :Roger{XY}", false},      // no ack number
+		{"N1CALL>APRS:!4903.50N/07201.75W>ackAB", false}, // not a message
+	}
+	for _, c := range cases {
+		p, _ := Parse(c.raw)
+		if got := MatchAck(sent, p); got != c.want {
+			t.Errorf("MatchAck(%q) = %v, want %v", c.raw, got, c.want)
+		}
+	}
+}
+
+func TestCompressionInfo(t *testing.T) {
+	cases := []struct {
+		tbyte  byte // type byte value before the +33 offset
+		fix    bool
+		nmea   string
+		origin string
+	}{
+		{0x00, false, "other", "compressed"},
+		{0x20 | 0x18 | 0x02, true, "RMC", "software"}, // current RMC fix from software
+		{0x08 | 0x04, false, "GLL", "kpc3"},
+		{0x20 | 0x10 | 0x05, true, "GGA", "pico"},
+		{0x07, false, "other", "digipeater"},
+	}
+	for _, c := range cases {
+		raw := "N0CALL>APRS:!/5L!!<*e7>7P" + string(rune(c.tbyte+33))
+		p, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", raw, err)
+		}
+		ci := p.CompressionInfo
+		if ci == nil {
+			t.Fatalf("%q: CompressionInfo is nil", raw)
+		}
+		if ci.Raw != c.tbyte || ci.GPSFix != c.fix || ci.NMEASource != c.nmea || ci.Origin != c.origin {
+			t.Errorf("type byte %#x: got %+v, want fix=%v nmea=%q origin=%q", c.tbyte, *ci, c.fix, c.nmea, c.origin)
+		}
+	}
+
+	if p, _ := Parse("N0CALL>APRS:!4903.50N/07201.75W>"); p.CompressionInfo != nil {
+		t.Error("uncompressed position has CompressionInfo")
+	}
+
+	// A blank course/speed byte makes the type byte meaningless.
+	if p, _ := Parse("N0CALL>APRS:!/5L!!<*e7>  " + string(rune(0x20|0x18|0x02+33))); p.Format != "compressed" || p.CompressionInfo != nil {
+		t.Errorf("blank cs: format %q, CompressionInfo = %+v", p.Format, p.CompressionInfo)
+	}
+}
+
+func TestUserDefinedApp(t *testing.T) {
+	cases := []struct{ raw, id, app, body string }{
+		{"N0CALL>APRS:{{Xpayload", "{", "experimental", "payload"},
+		{"N0CALL>APRS:{DT*ABC#", "D", "Dire Wolf raw touch tone data", "*ABC#"},
+		{"N0CALL>APRS:{DM-.-", "D", "Dire Wolf Morse code data", "-.-"},
+		{"N0CALL>APRS:{DZdata", "D", "Dire Wolf", "data"},
+		{"N0CALL>APRS:{Q1qwerty", "Q", "", "qwerty"},
+		{"N0CALL>APRS:{K", "K", "", ""},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.ID != c.id || p.UserDefinedApp != c.app || p.Body != c.body {
+			t.Errorf("%q: ID=%q app=%q body=%q, want %q %q %q", c.raw, p.ID, p.UserDefinedApp, p.Body, c.id, c.app, c.body)
+		}
+	}
+}
+
+func TestPositionlessWeatherTimestamp(t *testing.T) {
+	want := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	mdhm := want.Format("01021504")
+
+	p, err := Parse("SRC>APRS:_"+mdhm+"c220s004g005t077h50b10130", WithWarnings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.RawTimestamp != mdhm || int64(p.Timestamp) != want.Unix() {
+		t.Errorf("timestamp = %q/%d, want %q/%d", p.RawTimestamp, p.Timestamp, mdhm, want.Unix())
+	}
+	if p.Weather["pressure"] != 1013 {
+		t.Errorf("pressure = %v, want 1013", p.Weather["pressure"])
+	}
+
+	// An impossible date keeps the weather but not the time.
+	p, _ = Parse("SRC>APRS:_12345678c220s004g005t077h50b10130", WithWarnings())
+	if p.Timestamp != 0 || len(p.Warnings) != 1 || p.Warnings[0].Field != "timestamp" {
+		t.Errorf("invalid MDHM: Timestamp=%d warnings=%+v", p.Timestamp, p.Warnings)
+	}
+}
+
+func TestSymbolID(t *testing.T) {
+	cases := []struct{ raw, id, overlay string }{
+		{"N0CALL>APRS:!4903.50N/07201.75W>", "/>", ""},
+		{"N0CALL>APRS:!4903.50N\\07201.75W>", "\\>", ""},
+		{"N0CALL>APRS:!4903.50ND07201.75W^", "\\^", "D"},
+		// Compressed overlays a-j are the digits 0-9.
+		{"N0CALL>APRS:!a5L!!<*e7#  !", "\\#", "0"},
+		{"N0CALL>APRS:!j5L!!<*e7#  !", "\\#", "9"},
+		{"N0CALL>APRS:!A5L!!<*e7#  !", "\\#", "A"},
+		{"N0CALL>APRS:>status", "", ""},
+	}
+	for _, c := range cases {
+		p, _ := Parse(c.raw)
+		id, overlay := p.SymbolID()
+		if id != c.id || overlay != c.overlay {
+			t.Errorf("%q: SymbolID() = %q, %q; want %q, %q", c.raw, id, overlay, c.id, c.overlay)
+		}
+	}
+}
+
+func TestParseEmbeddedPositionBang(t *testing.T) {
+	cases := []struct {
+		raw     string
+		lat     float64
+		comment string
+	}{
+		// A '!' in leading text is skipped for the position-shaped one.
+		{"N0CALL>APRS:Hello! 1-2-3 !4903.50N/07201.75W>hello", 49.058333, "hello"},
+		{"N0CALL>APRS:Hi! !/5L!!<*e7>7P[", 49.5, ""},
+		// A regular position keeps the '!' in its comment.
+		{"N0CALL>APRS:!4903.50N/07201.75W>Testing! 1-2-3", 49.058333, "Testing! 1-2-3"},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if !p.HasPosition || !approx(p.Lat, c.lat, 1e-4) {
+			t.Errorf("%q: Lat = %f (HasPosition %v), want %f", c.raw, p.Lat, p.HasPosition, c.lat)
+		}
+		if p.Comment != c.comment {
+			t.Errorf("%q: Comment = %q, want %q", c.raw, p.Comment, c.comment)
+		}
+	}
+}
+
+func TestParseMovingCompressedObject(t *testing.T) {
+	cases := []struct {
+		raw           string
+		course, speed float64
+		comment       string
+	}{
+		// Course/speed from the cs bytes ("7P": 88 degrees, ~36 knots).
+		{"N0CALL>APRS:;LEADER   *092345z/5L!!<*e7>7P[146.520MHz T100 Event", 88, 67.10, "146.520MHz T100 Event"},
+		// Blank cs bytes: a CSE/SPD extension supplies the motion.
+		{"N0CALL>APRS:;LEADER   *092345z/5L!!<*e7>  [088/036146.520MHz Event", 88, 36 * 1.852, "146.520MHz Event"},
+		// The cs bytes win over a conflicting extension, which is stripped.
+		{"N0CALL>APRS:;LEADER   *092345z/5L!!<*e7>7P[270/010146.520MHz Event", 88, 67.10, "146.520MHz Event"},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.Format != "object" || p.ObjectFormat != "compressed" || p.ObjectName != "LEADER" || !p.Alive {
+			t.Errorf("%q: Format = %q/%q, ObjectName = %q, Alive = %v", c.raw, p.Format, p.ObjectFormat, p.ObjectName, p.Alive)
+		}
+		if p.Course != c.course || !approx(p.Speed, c.speed, 0.01) || p.IsFixed {
+			t.Errorf("%q: Course/Speed = %v/%v (fixed %v), want %v/%v", c.raw, p.Course, p.Speed, p.IsFixed, c.course, c.speed)
+		}
+		if p.Comment != c.comment {
+			t.Errorf("%q: Comment = %q, want %q", c.raw, p.Comment, c.comment)
+		}
+	}
+}
+
+func TestParseMessageAddresseePadding(t *testing.T) {
+	cases := []struct {
+		raw, addressee, text string
+		warned               bool
+	}{
+		{"N0CALL>APRS::WU2Z     :Testing{003", "WU2Z", "Testing", false},
+		{"N0CALL>APRS::N0CALL-10:Hello", "N0CALL-10", "Hello", false},
+		// Short and over-long padding from non-conformant senders.
+		{"N0CALL>APRS::WU2Z:Testing{003", "WU2Z", "Testing", true},
+		{"N0CALL>APRS::WU2Z  :Hi: there", "WU2Z", "Hi: there", true},
+		{"N0CALL>APRS::WU2Z-1      :Hello", "WU2Z-1", "Hello", true},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw, WithWarnings())
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.Addressee != c.addressee || p.MessageText != c.text {
+			t.Errorf("%q: Addressee/MessageText = %q/%q, want %q/%q", c.raw, p.Addressee, p.MessageText, c.addressee, c.text)
+		}
+		if warned := len(p.Warnings) > 0; warned != c.warned {
+			t.Errorf("%q: Warnings = %v, want warned %v", c.raw, p.Warnings, c.warned)
+		}
+	}
+
+	// Bulletins keep their own format.
+	p, _ := Parse("N0CALL>APRS::BLN1WX   :Storm warning")
+	if p.Format != "group-bulletin" || p.Addressee != "" {
+		t.Errorf("bulletin: Format = %q, Addressee = %q", p.Format, p.Addressee)
+	}
+}
+
+func TestParseAndCanonicalize(t *testing.T) {
+	equivalent := [][2]string{
+		// Header case, comment padding and extension order.
+		{
+			"N0CALL>APRS,WIDE1-1:!4903.50N/07201.75W>088/036 Hello/A=001234",
+			"n0call>aprs,wide1-1:!4903.50N/07201.75W>088/036/A=001234 Hello  ",
+		},
+		// Compressed and uncompressed encodings of the same report.
+		{
+			"N0CALL>APRS:!/5L!!<*e7>7P[Hi",
+			"N0CALL>APRS:!4930.00N/07245.00W>088/036Hi",
+		},
+		// Non-position packets only lose trailing spaces.
+		{"N0CALL>APRS:>Status text", "N0CALL>APRS:>Status text   "},
+	}
+	for _, pair := range equivalent {
+		_, a, err := ParseAndCanonicalize(pair[0])
+		if err != nil {
+			t.Fatalf("ParseAndCanonicalize(%q): %v", pair[0], err)
+		}
+		_, b, err := ParseAndCanonicalize(pair[1])
+		if err != nil {
+			t.Fatalf("ParseAndCanonicalize(%q): %v", pair[1], err)
+		}
+		if a != b {
+			t.Errorf("canonical forms differ:\n%q -> %q\n%q -> %q", pair[0], a, pair[1], b)
+		}
+	}
+
+	p, canonical, _ := ParseAndCanonicalize("N0CALL>APRS,WIDE1-1:!4903.50N/07201.75W>088/036 Hello/A=001234")
+	if want := "N0CALL>APRS,WIDE1-1:!4903.50N/07201.75W>088/036/A=001234Hello"; canonical != want {
+		t.Errorf("canonical = %q, want %q", canonical, want)
+	}
+	if p.Comment != "Hello" {
+		t.Errorf("Parsed.Comment = %q", p.Comment)
+	}
+
+	_, b, _ := ParseAndCanonicalize("N0CALL>APRS:!4903.50N/07201.75W>088/036 Bye")
+	if canonical == b {
+		t.Error("different packets share a canonical form")
+	}
+	if _, _, err := ParseAndCanonicalize("N0CALL"); err == nil {
+		t.Error("ParseAndCanonicalize accepted a packet without body")
+	}
+}
+
+func TestTelemetryDigitalStatesSense(t *testing.T) {
+	cfg, err := Parse("N0CALL>APRS::N0CALL   :BITS.01111111,Door monitor")
+	if err != nil {
+		t.Fatalf("Parse BITS: %v", err)
+	}
+	if cfg.TBITS != "01111111" {
+		t.Fatalf("TBITS = %q", cfg.TBITS)
+	}
+
+	p, err := Parse("N0CALL>APRS:T#005,199,000,255,073,123,00000001")
+	if err != nil {
+		t.Fatalf("Parse T#: %v", err)
+	}
+	states, ok := p.Telemetry.DigitalStates(cfg.TBITS)
+	// Channel 1 is inverted: its 0 is active. Channels 2-8 are active-high.
+	want := [8]bool{true, false, false, false, false, false, false, true}
+	if !ok || states != want {
+		t.Errorf("DigitalStates(%q) = %v, %v, want %v", cfg.TBITS, states, ok, want)
+	}
+
+	if states, ok := p.Telemetry.DigitalStates(""); !ok || states[0] || !states[7] {
+		t.Errorf("default sense: %v, %v", states, ok)
+	}
+	if _, ok := p.Telemetry.DigitalStates("0101"); ok {
+		t.Error("DigitalStates accepted a short sense pattern")
+	}
+	if _, ok := (TelemetryData{}).DigitalStates(""); ok {
+		t.Error("DigitalStates reported states without Bits")
+	}
+}
+
+func TestParseTelemetryInMessage(t *testing.T) {
+	p, err := Parse("N0CALL>APRS::N0CALL   :T#005,199,000,255,073,123,01101001{12")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p.Format != "telemetry" || !p.PacketType.Has(TypeTelemetry) || !p.PacketType.Has(TypeMessage) {
+		t.Errorf("Format = %q, PacketType = %b", p.Format, p.PacketType)
+	}
+	if p.Telemetry.Seq != 5 || len(p.Telemetry.Vals) != 5 || p.Telemetry.Vals[2] != 255 || p.Telemetry.Bits != "01101001" {
+		t.Errorf("Telemetry = %+v", p.Telemetry)
+	}
+	if p.Addressee != "N0CALL" || p.MsgNo != "12" {
+		t.Errorf("Addressee/MsgNo = %q/%q", p.Addressee, p.MsgNo)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	hash := func(raw string) string {
+		t.Helper()
+		p, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", raw, err)
+		}
+		return p.ContentHash()
+	}
+
+	a := hash("N0CALL>APRS,WIDE1-1,qAR,IGATE1:!4903.50N/07201.75W>Mobile")
+	if len(a) != 16 {
+		t.Errorf("ContentHash = %q, want 16 hex digits", a)
+	}
+	// Same packet via another path and igate, with trailing padding.
+	if b := hash("N0CALL>APRS,DIGI*,WIDE2-1,qAR,IGATE2:!4903.50N/07201.75W>Mobile  \r\n"); a != b {
+		t.Errorf("duplicates hash differently: %s != %s", a, b)
+	}
+	for _, raw := range []string{
+		"N0CALL>APRS,WIDE1-1:!4903.50N/07201.75W>Mobil",
+		"N0CALL-1>APRS,WIDE1-1:!4903.50N/07201.75W>Mobile",
+		"N0CALL>APRX,WIDE1-1:!4903.50N/07201.75W>Mobile",
+		"N0CALL>APRS:/092345z4903.50N/07201.75W>Mobile",
+	} {
+		if hash(raw) == a {
+			t.Errorf("%q shares the hash of a different packet", raw)
+		}
+	}
+
+	// The ID is stable across versions: FNV-1a 64 of "N0CALL\x00APRS\x00>Hello".
+	if got := hash("N0CALL>APRS:>Hello"); got != "6f036c33ab0e1c6f" {
+		t.Errorf("ContentHash = %s, want 6f036c33ab0e1c6f", got)
+	}
+}
+
+func TestTimestampForms(t *testing.T) {
+	ref := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		raw  string
+		ref  time.Time
+		want time.Time
+		zulu bool
+	}{
+		{"N0CALL>APRS:@092345z4903.50N/07201.75W>", ref, time.Date(2024, 3, 9, 23, 45, 0, 0, time.UTC), true},
+		{"N0CALL>APRS:@112233h4903.50N/07201.75W>", ref, time.Date(2024, 3, 15, 11, 22, 33, 0, time.UTC), true},
+		// Rollover: the 31st received on the 1st is last month.
+		{"N0CALL>APRS:/312345z4903.50N/07201.75W>", time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC), time.Date(2024, 3, 31, 23, 45, 0, 0, time.UTC), true},
+		// Rollover: 23:45:17 received at 00:30 is yesterday.
+		{"N0CALL>APRS:/234517h4903.50N/07201.75W>", time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC), time.Date(2024, 2, 29, 23, 45, 17, 0, time.UTC), true},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw, WithReferenceTime(c.ref))
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if int64(p.Timestamp) != c.want.Unix() || p.TimestampZulu != c.zulu {
+			t.Errorf("%q: Timestamp = %s (zulu %v), want %s (zulu %v)", c.raw,
+				time.Unix(int64(p.Timestamp), 0).UTC(), p.TimestampZulu, c.want.UTC(), c.zulu)
+		}
+	}
+
+	p, _ := Parse("N0CALL>APRS:@322345z4903.50N/07201.75W>", WithReferenceTime(ref), WithWarnings())
+	if p.Timestamp != 0 || len(p.Warnings) != 1 {
+		t.Errorf("day 32: Timestamp = %d, Warnings = %v", p.Timestamp, p.Warnings)
+	}
+}
+
+func TestLocalTimestamp(t *testing.T) {
+	const raw = "N0CALL>APRS:@151030/4903.50N/07201.75W>"
+	local := LocalTime{Day: 15, Hour: 10, Minute: 30}
+
+	// Without an assumed zone the wall-clock time is kept, not converted.
+	p, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p.Timestamp != 0 || p.TimestampZulu || p.TimestampLocal == nil || *p.TimestampLocal != local {
+		t.Errorf("no zone: Timestamp = %d, TimestampLocal = %+v", p.Timestamp, p.TimestampLocal)
+	}
+
+	// Nor does the reference time's zone stand in for one.
+	est := time.FixedZone("EST", -5*3600)
+	ref := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	p, err = Parse(raw, WithReferenceTime(ref.In(est)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p.Timestamp != 0 || p.TimestampLocal == nil || *p.TimestampLocal != local {
+		t.Errorf("reference zone: Timestamp = %d, TimestampLocal = %+v", p.Timestamp, p.TimestampLocal)
+	}
+
+	// An assumed zone converts it.
+	cet := time.FixedZone("CET", 3600)
+	p, err = Parse(raw, WithReferenceTime(ref.In(est)), WithAssumedTimezone(cet))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want := time.Date(2024, 3, 15, 10, 30, 0, 0, cet); int64(p.Timestamp) != want.Unix() {
+		t.Errorf("CET: Timestamp = %s, want %s", time.Unix(int64(p.Timestamp), 0).UTC(), want.UTC())
+	}
+	if p.TimestampLocal == nil || *p.TimestampLocal != local {
+		t.Errorf("CET: TimestampLocal = %+v", p.TimestampLocal)
+	}
+
+	// UTC timestamps have no local form.
+	p, _ = Parse("N0CALL>APRS:@151030z4903.50N/07201.75W>", WithAssumedTimezone(cet))
+	if p.TimestampLocal != nil {
+		t.Errorf("zulu: TimestampLocal = %+v", p.TimestampLocal)
+	}
+}
+
+func TestPositionedWeatherWind(t *testing.T) {
+	p, err := Parse("N0CALL>APRS:!4903.50N/07201.75W_220/004g005t077r000p000P000h50b09900")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p.Course != 0 || p.Speed != 0 {
+		t.Errorf("wind read as course/speed: %v/%v", p.Course, p.Speed)
+	}
+	if p.Weather["windDirection"] != 220 {
+		t.Errorf("windDirection = %v, want 220", p.Weather["windDirection"])
+	}
+	if _, ok := p.Weather["windSpeed"]; !ok {
+		t.Errorf("windSpeed missing: %v", p.Weather)
+	}
+	if p.Weather["temperature"] == 0 || p.RawCourseSpeed != "220/004" {
+		t.Errorf("Weather = %v, RawCourseSpeed = %q", p.Weather, p.RawCourseSpeed)
+	}
+}
+
+func TestRawPath(t *testing.T) {
+	cases := []struct {
+		raw, rawPath string
+		path         []string
+	}{
+		{"N0CALL>APRS,WIDE1-1,qAR,IGATE:>x", "WIDE1-1,qAR,IGATE", []string{"WIDE1-1", "qAR", "IGATE"}},
+		{"N0CALL>APRS,WIDE1-1,, ,qAR,IGATE,:>x", "WIDE1-1,, ,qAR,IGATE,", []string{"WIDE1-1", "qAR", "IGATE"}},
+		{"N0CALL>APRS:>x", "", nil},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.RawPath != c.rawPath || strings.Join(p.Path, ",") != strings.Join(c.path, ",") {
+			t.Errorf("%q: RawPath = %q, Path = %v; want %q, %v", c.raw, p.RawPath, p.Path, c.rawPath, c.path)
+		}
+	}
+}
+
+func TestParseSignalReport(t *testing.T) {
+	cases := []struct {
+		raw     string
+		report  *SignalReport
+		comment string
+	}{
+		{"N0CALL>APRS:!4903.50N/07201.75W>Mobile -87dBm", &SignalReport{Raw: "-87dBm", DBm: -87}, "Mobile"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>Heard -101.5 dBm ", &SignalReport{Raw: "-101.5 dBm", DBm: -101.5}, "Heard"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>RSSI S7", &SignalReport{Raw: "RSSI S7", SUnit: 7}, ""},
+		{"N0CALL>APRS:!4903.50N/07201.75W>Base sig S9+10", &SignalReport{Raw: "sig S9+10", SUnit: 9, Over: 10}, "Base"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>Base S9+20dB", &SignalReport{Raw: "S9+20dB", SUnit: 9, Over: 20}, "Base"},
+		{"OX8AAA>T7UU97:`(T4l!u>/]Mic-E -75dBm", &SignalReport{Raw: "-75dBm", DBm: -75}, "]Mic-E"},
+		// Not a signal report: left in the comment.
+		{"N0CALL>APRS:!4903.50N/07201.75W>-87dBm is typical here", nil, "-87dBm is typical here"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>Model X-87dBm", nil, "Model X-87dBm"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>Samsung S7+20", nil, "Samsung S7+20"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>Samsung Galaxy S9", nil, "Samsung Galaxy S9"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>Route S5", nil, "Route S5"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>RSSI S7+20", nil, "RSSI S7+20"},
+		{"N0CALL>APRS:!4903.50N/07201.75W>Mobile", nil, "Mobile"},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if (p.SignalReport == nil) != (c.report == nil) || (c.report != nil && *p.SignalReport != *c.report) {
+			t.Errorf("%q: SignalReport = %+v, want %+v", c.raw, p.SignalReport, c.report)
+		}
+		if !strings.HasSuffix(p.Comment, c.comment) {
+			t.Errorf("%q: Comment = %q, want %q", c.raw, p.Comment, c.comment)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for _, packet := range loadCorpus(t) {
+		buf := []byte(packet)
+		got, gotErr := ParseBytes(buf)
+		want, wantErr := Parse(packet)
+		if (gotErr == nil) != (wantErr == nil) || gotErr != nil && gotErr.Error() != wantErr.Error() {
+			t.Errorf("%q: ParseBytes error %v, Parse error %v", packet, gotErr, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: ParseBytes = %+v, Parse = %+v", packet, got, want)
+		}
+		// The result must not alias buf.
+		for i := range buf {
+			buf[i] = 'x'
+		}
+		if got.Raw != packet {
+			t.Errorf("%q: Raw changed to %q after reusing the buffer", packet, got.Raw)
+		}
+	}
+}
+
+// BenchmarkParseBytes parses the corpus from byte slices, as read from a
+// socket; compare with BenchmarkParse, whose input is already a string.
+func BenchmarkParseBytes(b *testing.B) {
+	packets := loadCorpus(b)
+	raw := make([][]byte, len(packets))
+	for i, p := range packets {
+		raw[i] = []byte(p)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ParseBytes(raw[i%len(raw)])
+	}
+}
+
+func TestParsePHGRate(t *testing.T) {
+	cases := []struct {
+		digit    string
+		rate     int
+		interval time.Duration
+	}{
+		{"1", 1, time.Hour},
+		{"4", 4, 15 * time.Minute},
+		{"A", 10, 6 * time.Minute},
+		{"F", 15, 4 * time.Minute},
+		{"Z", 35, time.Hour / 35},
+	}
+	for _, c := range cases {
+		p, err := Parse("N0CALL>APRS:!4903.50N/07201.75W#PHG5132" + c.digit + "/Digi")
+		if err != nil {
+			t.Fatalf("%s: %v", c.digit, err)
+		}
+		if p.PHG != "5132"+c.digit || p.PHGRate != c.rate || p.PHGInterval != c.interval || p.Comment != "Digi" {
+			t.Errorf("PHGR %s: PHG %q rate %d interval %v comment %q", c.digit, p.PHG, p.PHGRate, p.PHGInterval, p.Comment)
+		}
+	}
+
+	p, _ := Parse("N0CALL>APRS:!4903.50N/07201.75W#PHG5132 no rate")
+	if p.PHGRate != 0 || p.PHGInterval != 0 {
+		t.Errorf("plain PHG: rate %d interval %v", p.PHGRate, p.PHGInterval)
+	}
+}
+
+func TestParseTimestampedCompressed(t *testing.T) {
+	ref := WithReferenceTime(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+	want := time.Date(2024, 3, 9, 23, 45, 0, 0, time.UTC).Unix()
+	cases := []struct {
+		raw     string
+		comment string
+	}{
+		{"N0CALL>APRS:@092345z/5L!!<*e7>7P[Timestamped", "Timestamped"},
+		{"N0CALL>APRS:/092345z/5L!!<*e7>7P[No messaging", "No messaging"},
+		{"N0CALL>APRS:@092345zS5L!!<*e7>7P[Overlay", "Overlay"},
+		// Blank csT bytes trimmed off the end of the line.
+		{"N0CALL>APRS:@092345z/5L!!<*e7>", ""},
+		{"N0CALL>APRS:/092345z/5L!!<*e7> ", ""},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw, ref)
+		if err != nil {
+			t.Errorf("%q: %v", c.raw, err)
+			continue
+		}
+		if p.Format != "compressed" || !approx(p.Lat, 49.5, 1e-4) || !approx(p.Lon, -72.75, 1e-4) {
+			t.Errorf("%q: format %q position %v,%v", c.raw, p.Format, p.Lat, p.Lon)
+		}
+		if p.RawTimestamp != "092345z" || int64(p.Timestamp) != want || !p.TimestampZulu {
+			t.Errorf("%q: timestamp %q = %d, want %d", c.raw, p.RawTimestamp, p.Timestamp, want)
+		}
+		if p.Comment != c.comment {
+			t.Errorf("%q: comment %q, want %q", c.raw, p.Comment, c.comment)
+		}
+	}
+
+	// Course/speed bytes that are not blank cannot be restored.
+	if _, err := Parse("N0CALL>APRS:@092345z/5L!!<*e7>7", ref); err == nil {
+		t.Error("compressed block cut inside cs should fail")
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	packets := append(loadCorpus(t),
+		"",
+		"\r\n",
+		"N0CALL>APRS",
+		":>status",
+		"N0CALL>APRS:",
+		"N0CALL>APRS:>",
+		"N0CALL>APRS:!",
+		"N0CALL>APRS:%agrelo",
+		"N0CALL>APRS:!garbage",
+		"N0CALL>APRS:!4903.50N/07201.75W>ok",
+		"N0CALL>APRS:;OBJ      *111111z4903.50N/07201.75W-",
+		"N0CALL>APRS:;OBJ",
+		"N0CALL>APRS:)IT!4903.50N/07201.75W-",
+		"N0CALL>APRS:`(T4l!u>/]",
+		"N0CALL>APRS:_10090556c220s004g005t077",
+		"N0CALL>APRS:}X>Y:>inner",
+		"N0CALL>APRS:}broken",
+		"N0CALL>APRS:Hello!4903.50N/07201.75W-",
+		"N0CALL>APRS:Hello!garbage",
+		"N0CALL>APRS::N1CALL   :hi{1",
+		"bad call>APRS:>x",
+		"N0CALL>APRS,WIDE 1:>x",
+		// Beyond DefaultLimits.
+		"N0CALL>APRS:>"+strings.Repeat("x", 600),
+		"N0CALL>APRS::N1CALL   :"+strings.Repeat("x", 600),
+		"N0CALL>APRS:}A>B:}C>D:}E>F:}G>H:>too deep",
+		"N0CALL>APRS:}A>B:}C>D:}E>F:>deep enough",
+	)
+	for _, packet := range packets {
+		_, err := Parse(packet)
+		if got := IsValid(packet); got != (err == nil) {
+			t.Errorf("IsValid(%q) = %v, Parse error %v", packet, got, err)
+		}
+	}
+}
+
+func TestEncodeKilledObjectAndItem(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 23, 45, 0, 0, time.UTC)
+	pos := PositionReport{Lat: 49.058333, Lon: -72.029167, SymbolTable: "/", Symbol: "-", Comment: "Gone", Timestamp: &ts}
+
+	cases := []struct {
+		name   string
+		encode func(ObjectReport) (string, error)
+		report ObjectReport
+		want   string
+		format string
+	}{
+		{"object", EncodeObject, ObjectReport{Name: "LEADER", Position: pos},
+			";LEADER   *092345z4903.50N/07201.75W-Gone", "object"},
+		{"killed object", EncodeObject, ObjectReport{Name: "LEADER", Killed: true, Position: pos},
+			";LEADER   _092345z4903.50N/07201.75W-Gone", "object"},
+		{"killed item", EncodeItem, ObjectReport{Name: "AID #2", Killed: true, Position: PositionReport{
+			Lat: 49.058333, Lon: -72.029167, SymbolTable: "/", Symbol: "-", Comment: "Gone"}},
+			")AID #2_4903.50N/07201.75W-Gone", "item"},
+	}
+	ref := WithReferenceTime(ts.Add(time.Hour))
+	for _, c := range cases {
+		info, err := c.encode(c.report)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if info != c.want {
+			t.Errorf("%s: encoded %q, want %q", c.name, info, c.want)
+		}
+
+		p, err := Parse("N0CALL>APRS:"+info, ref)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q): %v", c.name, info, err)
+		}
+		if p.ObjectName != c.report.Name || p.Alive == c.report.Killed || p.Format != c.format {
+			t.Errorf("%s: name %q alive %v format %q", c.name, p.ObjectName, p.Alive, p.Format)
+		}
+		if !p.HasPosition || !approx(p.Lat, 49.058333, 1e-4) || !approx(p.Lon, -72.029167, 1e-4) || p.Comment != "Gone" {
+			t.Errorf("%s: position %v,%v (%v) comment %q", c.name, p.Lat, p.Lon, p.HasPosition, p.Comment)
+		}
+		if c.report.Position.Timestamp != nil && int64(p.Timestamp) != ts.Unix() {
+			t.Errorf("%s: timestamp %d, want %d", c.name, p.Timestamp, ts.Unix())
+		}
+	}
+
+	if _, err := EncodeObject(ObjectReport{Name: "NOTIME", Position: PositionReport{SymbolTable: "/", Symbol: "-"}}); err == nil {
+		t.Error("object without timestamp should fail")
+	}
+	if _, err := EncodeItem(ObjectReport{Name: "AB", Position: PositionReport{SymbolTable: "/", Symbol: "-"}}); err == nil {
+		t.Error("two-character item name should fail")
+	}
+	if _, err := EncodeItem(ObjectReport{Name: "TIMED", Position: pos}); err == nil {
+		t.Error("item with timestamp should fail")
+	}
+
+	// The poles and the antimeridian survive the round trip; 180W comes back
+	// as 180E, the same meridian.
+	for _, c := range []struct{ lat, lon, wantLon float64 }{
+		{90, 180, 180},
+		{-90, -180, 180},
+	} {
+		info, err := EncodeItem(ObjectReport{Name: "EDGE", Position: PositionReport{
+			Lat: c.lat, Lon: c.lon, SymbolTable: "/", Symbol: "-"}})
+		if err != nil {
+			t.Fatalf("EncodeItem(%v, %v): %v", c.lat, c.lon, err)
+		}
+		p, err := Parse("N0CALL>APRS:" + info)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", info, err)
+		}
+		if p.Lat != c.lat || p.Lon != c.wantLon {
+			t.Errorf("%q: position %v,%v, want %v,%v", info, p.Lat, p.Lon, c.lat, c.wantLon)
+		}
+	}
+}
+
+func TestParseUnknownDataType(t *testing.T) {
+	for _, info := range []string{
+		"~4903.50N/07201.75W-future type!",
+		"|x!4903.50N/07201.75W-",
+		"\"!4903.50N/07201.75W-",
+		"\x1d!4903.50N/07201.75W-",
+		"¿Que?!4903.50N/07201.75W-",
+	} {
+		p, err := Parse("N0CALL>APRS:" + info)
+		if err != nil {
+			t.Errorf("%q: %v", info, err)
+			continue
+		}
+		dt := string([]rune(info)[:1])
+		if p.Format != "unknown" || p.DataType != dt || p.HasPosition || p.PacketType != 0 {
+			t.Errorf("%q: format %q data type %q position %v type %v", info, p.Format, p.DataType, p.HasPosition, p.PacketType)
+		}
+		if p.Body != info[len(dt):] {
+			t.Errorf("%q: body %q", info, p.Body)
+		}
+	}
+
+	// Free text with an embedded position keeps the '!' heuristic.
+	p, _ := Parse("N0CALL>APRS:Hello!4903.50N/07201.75W-")
+	if p.DataType != "H" || p.Format != "uncompressed" || !p.HasPosition {
+		t.Errorf("beacon text: data type %q format %q position %v", p.DataType, p.Format, p.HasPosition)
+	}
+	if p, _ := Parse("N0CALL>APRS:>status"); p.DataType != ">" {
+		t.Errorf("status data type = %q", p.DataType)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	long := "N0CALL>APRS:>" + strings.Repeat("x", MaxISLineLength)
+	nested := "A>APRS:}B>APRS:}C>APRS:}D>APRS:}E>APRS:>deep"
+
+	cases := []struct {
+		name   string
+		packet string
+		opts   []Option
+		want   error
+	}{
+		{"long info", long, nil, ErrInfoTooLong},
+		{"long info, custom", "N0CALL>APRS:>" + strings.Repeat("x", 20), []Option{WithLimits(Limits{MaxInfoLength: 10})}, ErrInfoTooLong},
+		{"long info, unlimited", long, []Option{WithLimits(Limits{})}, nil},
+		{"nested", nested, nil, ErrThirdPartyTooDeep},
+		{"nested, within limit", nested, []Option{WithLimits(Limits{MaxThirdPartyDepth: 4})}, nil},
+		{"nested, unlimited", nested, []Option{WithLimits(Limits{})}, nil},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.packet, c.opts...)
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: error %v, want %v", c.name, err, c.want)
+		}
+		if p.From == "" {
+			t.Errorf("%s: header not decoded", c.name)
+		}
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	// Every reading of the clock moves it on by a millisecond.
+	defer func(orig func() time.Time) { limitNow = orig }(limitNow)
+	var ticks int64
+	limitNow = func() time.Time {
+		ticks++
+		return time.Unix(0, ticks*int64(time.Millisecond))
+	}
+	timeout := func(d time.Duration) Option { return WithLimits(Limits{Timeout: d}) }
+	const position = "N0CALL>APRS:!4903.50N/07201.75W>PHG5130/A=001234"
+
+	// The deadline is set, then checked before the body, between the five
+	// comment steps and at the end: seven readings after the first.
+	if _, err := Parse(position, timeout(7*time.Millisecond)); err != nil {
+		t.Errorf("within timeout: %v", err)
+	}
+	// Running out mid-comment stops the remaining steps.
+	ticks = 0
+	p, err := Parse(position, timeout(2*time.Millisecond))
+	if !errors.Is(err, ErrParseTimeout) {
+		t.Errorf("mid-comment: error %v, want ErrParseTimeout", err)
+	}
+	if p.PHG == "" || p.AltitudeSource != AltitudeNone {
+		t.Errorf("mid-comment: PHG %q, AltitudeSource %v; want only the first step run", p.PHG, p.AltitudeSource)
+	}
+	if _, err := Parse("A>APRS:}B>APRS:}C>APRS:>deep", timeout(time.Millisecond)); !errors.Is(err, ErrParseTimeout) {
+		t.Errorf("nested: error %v, want ErrParseTimeout", err)
+	}
+}
+
+func TestRepeaterInfo(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want *RepeaterInfo
+	}{
+		{"N0CALL>APRS:;438.500  *092345z4903.50N/07201.75Wr438.500MHz T088 +600 R40k Club rptr",
+			&RepeaterInfo{FrequencyMHz: 438.5, OffsetMHz: 6, ToneMode: "T", Tone: 88, RangeKm: 40}},
+		{"N0CALL>APRS:;146.940- *092345z4903.50N/07201.75WrT100 -060 R25m",
+			&RepeaterInfo{FrequencyMHz: 146.94, OffsetMHz: -0.6, ToneMode: "T", Tone: 100, RangeKm: 25 * 1.609344}},
+		{"N0CALL>APRS:!4903.50N/07201.75Wr147.195MHz D023",
+			&RepeaterInfo{FrequencyMHz: 147.195, ToneMode: "D", Tone: 23}},
+		{"N0CALL>APRS:)145.50 !4903.50N/07201.75Wr",
+			&RepeaterInfo{FrequencyMHz: 145.5}},
+		{"N0CALL>APRS:!4903.50N/07201.75Wr Repeater on the hill", nil},
+		{"N0CALL>APRS:>146.940MHz status is no position", nil},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw, WithReferenceTime(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)))
+		if err != nil {
+			t.Fatalf("%q: %v", c.raw, err)
+		}
+		got, ok := p.RepeaterInfo()
+		if c.want == nil {
+			if ok {
+				t.Errorf("%q: RepeaterInfo = %+v, want none", c.raw, got)
+			}
+			continue
+		}
+		if !ok || !approx(got.FrequencyMHz, c.want.FrequencyMHz, 1e-9) || !approx(got.OffsetMHz, c.want.OffsetMHz, 1e-9) ||
+			got.ToneMode != c.want.ToneMode || got.Tone != c.want.Tone || !approx(got.RangeKm, c.want.RangeKm, 1e-9) {
+			t.Errorf("%q: RepeaterInfo = %+v, %v, want %+v", c.raw, got, ok, c.want)
+		}
+	}
+}
+
+func TestWeatherTemperatureEdgeCases(t *testing.T) {
+	cases := []struct {
+		field   string
+		want    float64
+		present bool
+	}{
+		{"t032", 0, true},
+		{"t000", -32 / 1.8, true},
+		{"t-40", -40, true},
+		{"t-05", (-5 - 32) / 1.8, true},
+		{"t...", 0, false},
+		{"t   ", 0, false},
+		{"t120", (120 - 32) / 1.8, true},
+	}
+	for _, c := range cases {
+		raw := "N0CALL>APRS:_10090556c220s004g005" + c.field + "h50b10150"
+		p, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+		got, ok := p.Weather["temperature"]
+		if ok != c.present || (ok && !approx(got, c.want, 1e-9)) {
+			t.Errorf("%s: temperature = %v, %v; want %v, %v", c.field, got, ok, c.want, c.present)
+		}
+		// The fields after the temperature still decode.
+		if p.Weather["humidity"] != 50 || p.Weather["pressure"] != 1015 {
+			t.Errorf("%s: weather = %v", c.field, p.Weather)
+		}
+	}
+
+	p, err := Parse("N0CALL>APRS:!4903.50N/07201.75W_220/004g005t-40h50")
+	if err != nil || p.Weather["temperature"] != -40 || p.Weather["humidity"] != 50 {
+		t.Errorf("positioned weather: %v, %v", p.Weather, err)
+	}
+}
+
+func TestShouldGateToRF(t *testing.T) {
+	cases := []struct {
+		raw    string
+		ok     bool
+		reason string
+	}{
+		{"K1ABC>APRS,TCPIP*,qAC,T2TEST::W2XYZ    :hello{1", true, ""},
+		{"K1ABC>APRS,TCPXX*,qAX,T2TEST::W2XYZ    :hello{1", false, "path contains TCPXX"},
+		{"K1ABC>APRS,NOGATE,qAR,W3IG::W2XYZ    :hello", false, "path contains NOGATE"},
+		{"K1ABC>APRS,RFONLY,qAR,W3IG::W2XYZ    :hello", false, "path contains RFONLY"},
+		{"K1ABC>APRS,qAX,T2TEST::W2XYZ    :hello", false, "injected by an unverified client"},
+		{"N0IGT>APRS,TCPIP*,qAC,T2TEST:>status", false, "sent by N0IGT"},
+		{"N0IGT-0>APRS,TCPIP*,qAC,T2TEST:>status", false, "sent by N0IGT"},
+		// Heard on RF by another igate: the RF side already has it.
+		{"K1ABC>APRS,WIDE2-1,qAR,W3IG:!4903.50N/07201.75W>", false, "heard on RF"},
+		{"K1ABC>APRS,qAo,W3IG:!4903.50N/07201.75W>", false, "heard on RF"},
+		// Gated into APRS-IS by this igate: never send it back.
+		{"K1ABC>APRS,N0IGT*,qAC,T2TEST:!4903.50N/07201.75W>", false, "already passed through N0IGT"},
+		{"K1ABC>APRS,TCPIP*,qAC,T2TEST:}W2XYZ>APRS,NOGATE,TCPIP,K1ABC*::N0CALL   :hi", false, "third-party packet: path contains NOGATE"},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		ok, reason := ShouldGateToRF(p, "n0igt")
+		if ok != c.ok || !strings.EqualFold(reason, c.reason) {
+			t.Errorf("%q: ShouldGateToRF = %v, %q; want %v, %q", c.raw, ok, reason, c.ok, c.reason)
+		}
+	}
+}
+
+func TestStationRole(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{"K1ABC-9>APRS:!4903.50N/07201.75W>Mobile", RoleMobile},
+		{"K1ABC-13>APRS:!4903.50N/07201.75W_", RoleWeather},
+		{"K1ABC>APRS:_10090556c220s004g005t077", RoleWeather},
+		{"K1ABC>APRS:!4903.50N/07201.75W-Home", RoleHome},
+		{"K1ABC-7>APRS:!4903.50N/07201.75W[", RoleHT},
+		{"K1ABC-11>APRS:!4903.50N/07201.75WO", RoleBalloon},
+		{"K1ABC-1>APRS:!4903.50NS07201.75W#", RoleDigipeater},
+		{"K1ABC-10>APRS,qAR,K1ABC-10:!4903.50NI07201.75W&", RoleIGate},
+		// No telling symbol: fall back to the SSID.
+		{"K1ABC-9>APRS:>status", RoleMobile},
+		{"K1ABC-3>APRS:>status", ""},
+		// An object describes something else than its sender.
+		{"K1ABC>APRS:;WX1      *111111z4903.50N/07201.75W_", ""},
+	}
+	for _, c := range cases {
+		p, err := Parse(c.raw, WithStationRole())
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.raw, err)
+		}
+		if p.StationRole != c.want {
+			t.Errorf("%q: StationRole = %q, want %q", c.raw, p.StationRole, c.want)
+		}
+	}
+
+	if p, _ := Parse(cases[0].raw); p.StationRole != "" {
+		t.Errorf("StationRole without the option = %q", p.StationRole)
+	}
+}
+
+// TestSSIDSymbolsMatchRoles checks that aprsutils.SymbolForSSID and the SSID
+// roles follow the same convention.
+func TestSSIDSymbolsMatchRoles(t *testing.T) {
+	for ssid := 0; ssid < 16; ssid++ {
+		table, symbol, ok := aprsutils.SymbolForSSID(strconv.Itoa(ssid))
+		if got := symbolRoles[table+symbol]; ok && got != ssidRoles[ssid] {
+			t.Errorf("SSID %d: symbol %s%s is a %q, role is %q", ssid, table, symbol, got, ssidRoles[ssid])
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/APRSCN/aprsutils"
+)
+
+// isQConstruct reports whether a path element is a q-construct ("qXY").
+func isQConstruct(hop string) bool {
+	return len(hop) == 3 && hop[0] == 'q'
+}
+
+// QConstruct returns the q-construct in the path (e.g. "qAR") and the
+// callsign following it, or empty strings when the packet has none.
+func (p *Parsed) QConstruct() (string, string) {
+	for i, hop := range p.Path {
+		if isQConstruct(hop) {
+			if i+1 < len(p.Path) {
+				return hop, p.Path[i+1]
+			}
+			return hop, ""
+		}
+	}
+	return "", ""
+}
+
+// Unverified reports whether the packet was injected into APRS-IS by an
+// unverified client: its path carries the TCPXX marker or the server tagged
+// it with qAX. Such packets should not be trusted for SAR/EmComm use.
+func (p *Parsed) Unverified() bool {
+	for _, hop := range p.Path {
+		if strings.EqualFold(strings.TrimSuffix(hop, "*"), "TCPXX") {
+			return true
+		}
+	}
+	q, _ := p.QConstruct()
+	return q == "qAX"
+}
+
+// FromRF reports whether the packet was heard on RF and gated into APRS-IS,
+// as opposed to being injected directly over the internet. It follows the
+// q-construct semantics: qAR/qAr (igate heard it) and qAO/qAo (client-only or
+// send-only igate relayed it) are RF; qAC/qAX/qAU (direct client) are not;
+// qAS (no q-construct on arrival) counts as RF unless the path carries the
+// TCPIP/TCPXX marker of an internet client. Packets without a q-construct
+// are not considered RF.
+func (p *Parsed) FromRF() bool {
+	switch PathQuality(*p).Injection {
+	case InjectionRF, InjectionRelayed:
+		return true
+	case InjectionServer:
+		for _, hop := range p.Path {
+			if isQConstruct(hop) {
+				break
+			}
+			call := strings.ToUpper(strings.TrimSuffix(hop, "*"))
+			if call == "TCPIP" || call == "TCPXX" {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// Injection classifies how a packet entered APRS-IS, as told by its
+// q-construct.
+type Injection string
+
+const (
+	InjectionUnknown Injection = ""        // no (recognized) q-construct
+	InjectionClient  Injection = "client"  // qAC/qAX/qAU: a client connection sent it
+	InjectionRF      Injection = "rf"      // qAR/qAr: an igate heard it directly on RF
+	InjectionServer  Injection = "server"  // qAS: a server accepted it without a q-construct
+	InjectionRelayed Injection = "relayed" // qAO/qAo: relayed via a send-only/client-only gateway
+)
+
+// PathInfo summarizes how directly a packet reached APRS-IS.
+type PathInfo struct {
+	QConstruct string    // e.g. "qAR" ("" when absent)
+	Entry      string    // callsign following the q-construct
+	Injection  Injection // injection category derived from QConstruct
+	Hops       int       // RF digipeater hops used before reaching the igate
+	Score      float64   // directness in [0,1]; 1 is a direct, hop-free injection
+}
+
+// genericAliasRe matches generic digipeating aliases such as WIDE2-1 or
+// TRACE3-3, capturing the n and N of "n-N".
+var genericAliasRe = aprsutils.CompiledRegexps.MustCompile(`^(?:WIDE|TRACE|RELAY)(\d)?(?:-(\d))?\*?$`)
+
+// PathQuality scores how directly p's path reached APRS-IS. Hops counts the
+// digipeaters that inserted their callsign before the last used ('*') hop;
+// when none did, it falls back to the deficit of the WIDEn-N aliases used up
+// to that hop. Aliases past it are not counted: an unused path such as
+// WIDE1-1,WIDE2-1 is 0 hops.
+// The score is 1/(1+Hops), halved for relayed injections and zero when the
+// injection is unknown.
+func PathQuality(p Parsed) PathInfo {
+	info := PathInfo{}
+	info.QConstruct, info.Entry = p.QConstruct()
+
+	if info.QConstruct != "" {
+		switch info.QConstruct[2] {
+		case 'C', 'X', 'U':
+			info.Injection = InjectionClient
+		case 'R', 'r':
+			info.Injection = InjectionRF
+		case 'S':
+			info.Injection = InjectionServer
+		case 'O', 'o':
+			info.Injection = InjectionRelayed
+		}
+	}
+
+	info.Hops = rfHops(p.Path)
+
+	switch info.Injection {
+	case InjectionUnknown:
+		info.Score = 0
+	case InjectionRelayed:
+		info.Score = 0.5 / float64(1+info.Hops)
+	default:
+		info.Score = 1 / float64(1+info.Hops)
+	}
+
+	return info
+}
+
+// rfHops counts digipeater hops in the part of path preceding the
+// q-construct.
+func rfHops(path []string) int {
+	before := path
+	for i, hop := range path {
+		if isQConstruct(hop) {
+			before = path[:i]
+			break
+		}
+	}
+
+	lastUsed := -1
+	for i, hop := range before {
+		if strings.HasSuffix(hop, "*") {
+			lastUsed = i
+		}
+	}
+
+	traced, deficit := 0, 0
+	for i, hop := range before {
+		call := strings.TrimSuffix(hop, "*")
+		if call == "TCPIP" || call == "TCPXX" {
+			continue
+		}
+		if m := genericAliasRe.FindStringSubmatch(hop); m != nil {
+			// A used WIDEn-N reveals n-N untraced hops (n once exhausted).
+			// One past the last used hop may be just as the sender set it,
+			// e.g. WIDE2-1, and says nothing.
+			if i <= lastUsed && m[1] != "" {
+				n, _ := strconv.Atoi(m[1])
+				left := 0
+				if m[2] != "" {
+					left, _ = strconv.Atoi(m[2])
+				}
+				if n > left {
+					deficit += n - left
+				}
+			}
+			continue
+		}
+		if i <= lastUsed {
+			traced++
+		}
+	}
+
+	if traced > 0 {
+		return traced
+	}
+	return deficit
+}
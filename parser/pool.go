@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Reset clears every field of p, including the weather map, slices and
+// SubPacket, so that it is indistinguishable from a fresh Parsed. Callers
+// reusing Parsed values (e.g. from a sync.Pool) must call Reset before each
+// reuse; nothing else is guaranteed to clear state left by a previous packet.
+func (p *Parsed) Reset() {
+	*p = Parsed{}
+}
+
+// ReadCorpus reads a packet corpus: one raw packet per line, skipping blank
+// lines and '#' comment lines. It is meant for benchmarks and replaying
+// captured feeds.
+func ReadCorpus(r io.Reader) ([]string, error) {
+	var packets []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		packets = append(packets, line)
+	}
+	return packets, scanner.Err()
+}
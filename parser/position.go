@@ -6,23 +6,48 @@ import (
 	"strconv"
 	"strings"
 
-	"go.gh.ink/regexp"
-
 	"github.com/APRSCN/aprsutils"
 	"github.com/APRSCN/aprsutils/utils"
 )
 
+// positionBangLimit is how far into a body without a data type character an
+// embedded '!' position may start.
+const positionBangLimit = 40
+
+// positionBang returns the index of the '!' starting an embedded position in
+// a body whose data type character is missing, or -1. A '!' followed by an
+// uncompressed or compressed position is preferred over the first literal
+// '!', so text such as "Testing! 1-2-3" ahead of the position is skipped.
+func positionBang(body string) int {
+	first := -1
+	for i := 0; i < len(body) && i < positionBangLimit; i++ {
+		if body[i] != '!' {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if aprsutils.CompiledRegexps.MustCompile(`^[0-9\s]{4}\.[0-9\s]{2}[NS].[0-9\s]{5}\.[0-9\s]{2}[EW]`).MatchString(body[i+1:]) ||
+			aprsutils.CompiledRegexps.MustCompile(`^[\/\\A-Za-j][!-{]{8}[!-~]`).MatchString(body[i+1:]) {
+			return i
+		}
+	}
+	return first
+}
+
 // parsePosition parses position format APRS packet
 func (p *Parsed) parsePosition(packetType string, body string) error {
 	// Check format
 	if !strings.Contains("!=/@;", packetType) {
 		packetType = "!"
-		_, body, _ = utils.SplitOnce(body, "!")
+		if i := positionBang(body); i >= 0 {
+			body = body[i+1:]
+		}
 	}
 
 	// Attempt to parse object report format
 	if packetType == ";" {
-		matches := regexp.MustCompile(`^([ -~]{9})(\*|_)`).FindStringSubmatch(body)
+		matches := aprsutils.CompiledRegexps.MustCompile(`^([ -~]{9})(\*|_)`).FindStringSubmatch(body)
 		if len(matches) >= 3 {
 			name := matches[1]
 			flag := matches[2]
@@ -54,7 +79,7 @@ func (p *Parsed) parsePosition(packetType string, body string) error {
 
 	// Decode body
 	var err error
-	if regexp.MustCompile(`^[0-9\s]{4}\.[0-9\s]{2}[NS].[0-9\s]{5}\.[0-9\s]{2}[EW]`).MatchString(body) {
+	if aprsutils.CompiledRegexps.MustCompile(`^[0-9\s]{4}\.[0-9\s]{2}[NS].[0-9\s]{5}\.[0-9\s]{2}[EW]`).MatchString(body) {
 		body, err = p.parseNormal(body)
 		if err != nil {
 			return err
@@ -74,12 +99,24 @@ func (p *Parsed) parsePosition(packetType string, body string) error {
 
 	// Check for weather info
 	if p.Symbol[0] == "_" {
-		// Attempt to parse winddir/speed
-		// Page 92 of the spec
-		body = p.parseDataExtensions(body)
+		// Page 92 of the spec: for a weather station "ddd/ddd" is the wind
+		// direction/speed, not course/speed, so it is left to
+		// parseWeatherData rather than parseDataExtensions. It is still
+		// recorded verbatim.
+		if len(body) >= 7 && body[3] == '/' {
+			if m := aprsutils.CompiledRegexps.MustCompile(`^([0-9 \.]{3})/([0-9 \.]{3})`).FindString(body); m != "" {
+				p.RawCourseSpeed = m
+			}
+		}
 		p.parseWeatherData(body)
 	} else {
+		// Course/speed encoded in the compressed cs bytes wins over a CSE/SPD
+		// extension in the comment, as format altitude wins over "/A=".
+		course, speed := p.Course, p.Speed
 		p.parseComment(body)
+		if p.Format == "compressed" && course != 0 {
+			p.Course, p.Speed, p.IsFixed = course, speed, false
+		}
 	}
 
 	// Object
@@ -91,10 +128,84 @@ func (p *Parsed) parsePosition(packetType string, body string) error {
 	return nil
 }
 
+// normalizeLat clamps a decoded latitude to [-90, 90]. The compressed encoding
+// can overshoot the poles by a few hundredths of a degree.
+func normalizeLat(lat float64) float64 {
+	return max(-90, min(90, lat))
+}
+
+// zeroMinutes reports whether the minutes of an uncompressed coordinate are
+// all zero, ambiguity spaces aside, as they must be at 90 and 180 degrees.
+func zeroMinutes(minutes string) bool {
+	return strings.Trim(minutes, "0. ") == ""
+}
+
+// normalizeLon wraps a decoded longitude into (-180, 180], so that positions
+// at or past the antimeridian come out as map libraries expect. In-range
+// values are returned unchanged.
+func normalizeLon(lon float64) float64 {
+	if lon > -180 && lon <= 180 {
+		return lon
+	}
+	lon = math.Mod(lon+180, 360)
+	if lon <= 0 {
+		lon += 360
+	}
+	return lon - 180
+}
+
+// CompressionInfo is the decoded compression type byte ("T") of a compressed
+// position (aprs101.pdf ch. 9). The spec treats it as meaningful only when the
+// course/speed byte "c" is not a space, so Parsed.CompressionInfo is nil when
+// it is.
+type CompressionInfo struct {
+	Raw        byte   // type byte value (character minus 33)
+	GPSFix     bool   // current fix (true) or old/last fix (false)
+	NMEASource string // "other", "GLL", "GGA" or "RMC"
+	Origin     string // see compressionOrigins
+}
+
+// compressionNMEASources and compressionOrigins index the NMEA source (bits
+// 3-4) and compression origin (bits 0-2) of the type byte.
+var (
+	compressionNMEASources = [4]string{"other", "GLL", "GGA", "RMC"}
+	compressionOrigins     = [8]string{
+		"compressed",    // 000
+		"tnc-btext",     // 001 TNC BText
+		"software",      // 010 DOS/Mac/Win/+SA
+		"tbd",           // 011
+		"kpc3",          // 100
+		"pico",          // 101
+		"other-tracker", // 110
+		"digipeater",    // 111 digipeater conversion
+	}
+)
+
+// newCompressionInfo decodes the compression type byte value t.
+func newCompressionInfo(t int) *CompressionInfo {
+	return &CompressionInfo{
+		Raw:        byte(t),
+		GPSFix:     t&0x20 == 0x20,
+		NMEASource: compressionNMEASources[(t>>3)&0x03],
+		Origin:     compressionOrigins[t&0x07],
+	}
+}
+
+// MaxPlausibleAltitude is the highest altitude, in metres, accepted from
+// compressed cs bytes. It leaves headroom above the ~50 km reached by
+// high-altitude balloons.
+const MaxPlausibleAltitude = 60000.0
+
 // parseCompressed parses compressed APRS packet
 func (p *Parsed) parseCompressed(body string) (string, error) {
 	// Attempt to parse as compressed position report
-	// Check length
+	// Check length. A report without course/speed ends in blank "csT" bytes,
+	// which are lost when the line has its trailing spaces trimmed on the way
+	// (common for "@"/"/" beacons with nothing after the position); restore
+	// them, but only when what remains of cs is blank.
+	if n := utils.StringLen(body); n >= 10 && n < 13 && strings.TrimSpace(string([]rune(body)[10:])) == "" {
+		body += strings.Repeat(" ", 13-n)
+	}
 	if utils.StringLen(body) < 13 {
 		return body, errors.New("invalid compressed format")
 	}
@@ -114,10 +225,8 @@ func (p *Parsed) parseCompressed(body string) (string, error) {
 	}
 
 	// The 4-byte lat/lon groups must be printable base-91 digits in '!'..'{'.
-	for i := 1; i <= 8; i++ {
-		if compressed[i] < '!' || compressed[i] > '{' {
-			return body, errors.New("invalid compressed coordinates")
-		}
+	if !aprsutils.IsBase91(string(compressed[1:9])) {
+		return body, errors.New("invalid compressed coordinates")
 	}
 
 	// Set format
@@ -147,6 +256,10 @@ func (p *Parsed) parseCompressed(body string) (string, error) {
 	s1 := int(compressed[11]) - 33
 	ctype := int(compressed[12]) - 33
 
+	if c1 != -1 && ctype >= 0 && ctype <= 0x3f {
+		p.CompressionInfo = newCompressionInfo(ctype)
+	}
+
 	if c1 == -1 {
 		if ctype&0x20 == 0x20 {
 			p.GPSFixStatus = true
@@ -158,7 +271,16 @@ func (p *Parsed) parseCompressed(body string) (string, error) {
 	if c1 == -1 || s1 == -1 {
 		// Do nothing
 	} else if ctype&0x18 == 0x10 {
-		p.Altitude = math.Pow(1.002, float64(c1*91+s1)) * 0.3048
+		// The cs bytes encode altitude in feet as 1.002^cs; 0.3048 converts
+		// feet to metres. Corrupt bytes can decode to absurd heights, so keep
+		// only plausible values (see MaxPlausibleAltitude).
+		altitude := math.Pow(1.002, float64(c1*91+s1)) * 0.3048
+		if altitude <= MaxPlausibleAltitude {
+			p.Altitude = altitude
+			p.AltitudeSource = AltitudeCompressed
+		} else {
+			p.warn("altitude", "implausible compressed altitude dropped")
+		}
 	} else if c1 >= 0 && c1 <= 89 {
 		course := 360
 		if c1 != 0 {
@@ -173,8 +295,8 @@ func (p *Parsed) parseCompressed(body string) (string, error) {
 	}
 
 	p.Symbol = []string{symbol, symbolTable}
-	p.Lon = longitude
-	p.Lat = latitude
+	p.Lon = normalizeLon(longitude)
+	p.Lat = normalizeLat(latitude)
 
 	return body, nil
 }
@@ -184,7 +306,7 @@ func (p *Parsed) parseNormal(body string) (string, error) {
 	pattern := `^(\d{2})([0-9 ]{2}\.[0-9 ]{2})([NnSs])([\/\\0-9A-Z])` +
 		`(\d{3})([0-9 ]{2}\.[0-9 ]{2})([EeWw])([\x21-\x7e])(.*)$`
 
-	re := regexp.MustCompile(pattern)
+	re := aprsutils.CompiledRegexps.MustCompile(pattern)
 	matches := re.FindStringSubmatch(body)
 
 	if len(matches) < 10 {
@@ -221,7 +343,7 @@ func (p *Parsed) parseNormal(body string) (string, error) {
 	if err != nil {
 		return body, errors.New("invalid latitude degrees")
 	}
-	if latDegInt > 89 || latDegInt < 0 {
+	if latDegInt > 90 || latDegInt < 0 || (latDegInt == 90 && !zeroMinutes(matches[2])) {
 		return body, errors.New("latitude is out of range (0-90 degrees)")
 	}
 
@@ -229,7 +351,7 @@ func (p *Parsed) parseNormal(body string) (string, error) {
 	if err != nil {
 		return body, errors.New("invalid longitude degrees")
 	}
-	if lonDegInt > 179 || lonDegInt < 0 {
+	if lonDegInt > 180 || lonDegInt < 0 || (lonDegInt == 180 && !zeroMinutes(matches[6])) {
 		return body, errors.New("longitude is out of range (0-180 degrees)")
 	}
 
@@ -246,6 +368,14 @@ func (p *Parsed) parseNormal(body string) (string, error) {
 	}
 	longitude := float64(lonDegInt) + (lonMinFloat / 60.0)
 
+	// An ambiguous pole or antimeridian stays on it.
+	if latDegInt == 90 {
+		latitude = 90
+	}
+	if lonDegInt == 180 {
+		longitude = 180
+	}
+
 	if strings.Contains("Ss", string([]rune(latDir)[0])) {
 		latitude *= -1
 	}
@@ -255,8 +385,8 @@ func (p *Parsed) parseNormal(body string) (string, error) {
 
 	// Save result
 	p.Symbol = []string{symbol, symbolTable}
-	p.Lon = longitude
-	p.Lat = latitude
+	p.Lon = normalizeLon(longitude)
+	p.Lat = normalizeLat(latitude)
 
 	return remainingBody, nil
 }
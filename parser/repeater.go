@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/APRSCN/aprsutils"
+)
+
+// RepeaterInfo is the tuning data of a voice repeater announced in the APRS
+// frequency format (http://www.aprs.org/info/freqspec.txt).
+type RepeaterInfo struct {
+	FrequencyMHz float64
+	OffsetMHz    float64 // signed transmit offset; 0 when not given
+	ToneMode     string  // "T" CTCSS encode, "t" tone squelch, "D" DCS; "" when none
+	Tone         int     // CTCSS tone in whole Hz as sent (88 for 88.5), or the DCS code
+	RangeKm      float64 // usable range; 0 when not given
+}
+
+var (
+	// repeaterCommentRe matches a frequency at the start of a comment,
+	// "146.940MHz T100 -060 R25m".
+	repeaterCommentRe = aprsutils.CompiledRegexps.MustCompile(`^(\d{3}\.\d{3}) ?MHz(?:\s+|$)`)
+	// repeaterNameRe matches an object or item named after its frequency,
+	// "146.940-" or "438.500rx".
+	repeaterNameRe = aprsutils.CompiledRegexps.MustCompile(`^(\d{3}\.\d{2,3})`)
+	// repeaterToneRe, repeaterOffsetRe and repeaterRangeRe match the fields
+	// that may follow the frequency: "T088", "t088" or "D023"; "+060" or
+	// "-500" in 10 kHz steps; "R25m" (miles) or "R40k" (kilometres).
+	repeaterToneRe   = aprsutils.CompiledRegexps.MustCompile(`^([TtD])(\d{3})$`)
+	repeaterOffsetRe = aprsutils.CompiledRegexps.MustCompile(`^[+-]\d{3}$`)
+	repeaterRangeRe  = aprsutils.CompiledRegexps.MustCompile(`^R(\d{1,3})([mk])$`)
+)
+
+// RepeaterInfo returns the repeater tuning data of a packet whose comment
+// starts with a frequency ("438.500MHz T088 +500"), or of an object or item
+// named after one (";146.940- *...T100 -060"). The tone, offset and range
+// fields are read from the start of the comment, up to the first word that
+// is none of them. ok is false when the packet carries no frequency.
+func (p *Parsed) RepeaterInfo() (*RepeaterInfo, bool) {
+	comment := strings.TrimSpace(p.Comment)
+	var freq string
+	if m := repeaterCommentRe.FindStringSubmatch(comment); m != nil {
+		freq, comment = m[1], comment[len(m[0]):]
+	} else if m := repeaterNameRe.FindStringSubmatch(p.ObjectName); m != nil && p.PacketType.Has(TypeObject|TypeItem) {
+		freq = m[1]
+	} else {
+		return nil, false
+	}
+
+	info := &RepeaterInfo{}
+	info.FrequencyMHz, _ = strconv.ParseFloat(freq, 64)
+	for _, field := range strings.Fields(comment) {
+		if m := repeaterToneRe.FindStringSubmatch(field); m != nil && info.ToneMode == "" {
+			info.ToneMode = m[1]
+			info.Tone, _ = strconv.Atoi(m[2])
+		} else if repeaterOffsetRe.MatchString(field) && info.OffsetMHz == 0 {
+			steps, _ := strconv.Atoi(field)
+			info.OffsetMHz = float64(steps) / 100
+		} else if m := repeaterRangeRe.FindStringSubmatch(field); m != nil && info.RangeKm == 0 {
+			r, _ := strconv.Atoi(m[1])
+			info.RangeKm = float64(r)
+			if m[2] == "m" {
+				info.RangeKm *= 1.609344
+			}
+		} else {
+			break
+		}
+	}
+	return info, true
+}
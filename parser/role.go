@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"strconv"
+
+	"github.com/APRSCN/aprsutils"
+)
+
+// Station roles set in Parsed.StationRole by WithStationRole.
+const (
+	RoleHome       = "home"
+	RoleMobile     = "mobile"
+	RoleHT         = "ht"
+	RoleIGate      = "igate"
+	RoleWeather    = "weather"
+	RoleBalloon    = "balloon"
+	RoleDigipeater = "digipeater"
+)
+
+// symbolRoles maps symbols (as returned by SymbolID) to the role they
+// announce.
+var symbolRoles = map[string]string{
+	"/O": RoleBalloon,
+	"/_": RoleWeather, "\\_": RoleWeather,
+	"/#": RoleDigipeater, "\\#": RoleDigipeater,
+	"/&": RoleIGate, "\\&": RoleIGate,
+	"/-": RoleHome, "\\-": RoleHome,
+	"/[": RoleHT,
+	"/>": RoleMobile, "\\>": RoleMobile, // car
+	"/<": RoleMobile,                    // motorcycle
+	"/U": RoleMobile,                    // bus
+	"/a": RoleMobile,                    // ambulance
+	"/b": RoleMobile,                    // bicycle
+	"/f": RoleMobile,                    // fire truck
+	"/j": RoleMobile, "\\j": RoleMobile, // jeep
+	"/k": RoleMobile, "\\k": RoleMobile, // truck
+	"/u": RoleMobile, "\\u": RoleMobile, // 18 wheeler
+	"/v": RoleMobile, "\\v": RoleMobile, // van
+	"/R": RoleMobile,                    // recreational vehicle
+	"/s": RoleMobile, "\\s": RoleMobile, // ship
+	"/Y": RoleMobile, // yacht
+}
+
+// ssidRoles maps source SSIDs to the role the de-facto SSID conventions
+// (http://www.aprs.org/aprs11/SSIDs.txt) give them. No SSID is the station's
+// primary, usually fixed, station.
+var ssidRoles = map[int]string{
+	0:  RoleHome,
+	7:  RoleHT,
+	8:  RoleMobile, // boats, RVs
+	9:  RoleMobile,
+	10: RoleIGate,
+	11: RoleBalloon,
+	13: RoleWeather,
+	14: RoleMobile, // truckers
+}
+
+// WithStationRole fills Parsed.StationRole with a guess at the sending
+// station's role. It is a heuristic: stations are free to pick any SSID and
+// symbol, and many do.
+func WithStationRole() Option {
+	return func(p *config) {
+		p.stationRole = true
+	}
+}
+
+// stationRole guesses the role of the station that sent p, from the
+// strongest signal to the weakest: weather data, the symbol, an igate
+// gating its own packet (its callsign follows the q-construct), and finally
+// the SSID. Objects and items describe something else, so they get none.
+func stationRole(p *Parsed) string {
+	if p.PacketType.Has(TypeObject | TypeItem) {
+		return ""
+	}
+	if p.PacketType.Has(TypeWeather) {
+		return RoleWeather
+	}
+	if id, _ := p.SymbolID(); symbolRoles[id] != "" {
+		return symbolRoles[id]
+	}
+	if q, entry := p.QConstruct(); (q == "qAR" || q == "qAr" || q == "qAO") && aprsutils.SameStation(entry, p.From, false) {
+		return RoleIGate
+	}
+
+	_, ssid := aprsutils.SplitCallsign(p.From)
+	if ssid == "" {
+		return RoleHome
+	}
+	if n, err := strconv.Atoi(ssid); err == nil {
+		return ssidRoles[n]
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/APRSCN/aprsutils"
+)
+
+// BaseCall returns the source callsign without its SSID, preserving case.
+func (p *Parsed) BaseCall() string {
+	base, _ := aprsutils.SplitCallsign(p.From)
+	return base
+}
+
+// SSID returns the SSID of the source callsign ("" when absent).
+func (p *Parsed) SSID() string {
+	_, ssid := aprsutils.SplitCallsign(p.From)
+	return ssid
+}
+
+// StationKey returns the canonical grouping key of the source station: the
+// upper-cased base callsign, shared by every SSID of the same operator.
+func (p *Parsed) StationKey() string {
+	return aprsutils.StationKey(p.From)
+}
+
+// AddresseeKey returns the canonical grouping key of the message addressee
+// ("" for non-message packets).
+func (p *Parsed) AddresseeKey() string {
+	return aprsutils.StationKey(p.Addressee)
+}
+
+// ObjectKey returns the canonical grouping key of the object or item name
+// ("" for packets that carry neither): the trimmed, upper-cased full name.
+// Names are not callsigns, so a '-' is part of the name, not an SSID.
+func (p *Parsed) ObjectKey() string {
+	return strings.ToUpper(strings.TrimSpace(p.ObjectName))
+}
+
+// ContentHash returns a stable ID for the logical packet, usable as a
+// database key: the 16 hex digit FNV-1a 64-bit hash of
+//
+//	upper(From) NUL upper(To) NUL info
+//
+// where info is the information field of Raw (everything after the first
+// ':', including any timestamp) with trailing spaces, CR and LF removed. The
+// path and q-construct are left out, so copies of a packet heard through
+// different digipeaters or igates share an ID. These inputs and the hash are
+// fixed; IDs stay valid across library versions.
+func (p *Parsed) ContentHash() string {
+	_, info, _ := strings.Cut(p.Raw, ":")
+	info = strings.TrimRight(info, " \r\n")
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strings.ToUpper(p.From)))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(strings.ToUpper(p.To)))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(info))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,26 @@
+package parser
+
+// SymbolID returns the packet's symbol as the conventional two-character
+// identifier, table first ("/>" car, "\>" alternate-table car), together with
+// the overlay character when the alternate table is overlaid (a digit or
+// letter in place of '\'). For an overlaid symbol id uses '\' and overlay
+// holds the character, e.g. "D^" yields ("\^", "D"). Compressed positions
+// write overlay digits 0-9 as the letters a-j; they are reported as the
+// digits. Both are empty when the packet has no symbol.
+//
+// It hides the ordering of Parsed.Symbol, which is [code, table].
+func (p *Parsed) SymbolID() (id string, overlay string) {
+	if len(p.Symbol) != 2 || len(p.Symbol[0]) != 1 || len(p.Symbol[1]) != 1 {
+		return "", ""
+	}
+	code, table := p.Symbol[0], p.Symbol[1]
+	switch table {
+	case "/", "\\":
+		return table + code, ""
+	default:
+		if t := table[0]; t >= 'a' && t <= 'j' {
+			table = string('0' + t - 'a')
+		}
+		return "\\" + code, table
+	}
+}
@@ -5,28 +5,108 @@ import (
 	"strconv"
 	"strings"
 
-	"go.gh.ink/regexp"
-
 	"github.com/APRSCN/aprsutils"
 	"github.com/APRSCN/aprsutils/utils"
 )
 
+// telemetrySeqModulus is the wrap point of "T#" report sequence numbers,
+// which count 000..999 and then roll over.
+const telemetrySeqModulus = 1000
+
+// base91SeqModulus is the wrap point of comment telemetry sequence numbers,
+// a two-character base-91 value counting 0..8280.
+const base91SeqModulus = 91 * 91
+
 // TelemetryData is the struct for telemetry data
 type TelemetryData struct {
+	// RawSeq is the sequence token as sent ("005", "MIC", or the base-91
+	// pair of comment telemetry).
+	RawSeq string
+	// Seq is the numeric sequence (0 when RawSeq is "MIC").
 	Seq  int
 	Vals []int
 	Bits string
+	// Base91 marks comment telemetry ("|ss1122|"), whose sequence is a
+	// base-91 pair rather than a decimal "T#" counter.
+	Base91 bool
+}
+
+// HasSeq reports whether the report carries a numeric sequence number.
+// Mic-E style "MIC" reports, non-numeric tokens and packets without
+// telemetry do not.
+func (t TelemetryData) HasSeq() bool {
+	if t.Base91 {
+		return len(t.RawSeq) == 2
+	}
+	if t.RawSeq == "" {
+		return false
+	}
+	for i := 0; i < len(t.RawSeq); i++ {
+		if t.RawSeq[i] < '0' || t.RawSeq[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// seqModulus returns the value at which t's sequence number wraps.
+func (t TelemetryData) seqModulus() int {
+	if t.Base91 {
+		return base91SeqModulus
+	}
+	return telemetrySeqModulus
+}
+
+// SeqGap returns how many frames were lost between prev and t, handling the
+// rollover of the sequence (999 -> 000 for "T#" reports, 8280 -> 0 for
+// comment telemetry; 0 means t directly follows prev). ok is false when
+// either report lacks a numeric sequence or the two come from different
+// telemetry forms.
+func (t TelemetryData) SeqGap(prev TelemetryData) (gap int, ok bool) {
+	if !t.HasSeq() || !prev.HasSeq() || t.Base91 != prev.Base91 {
+		return 0, false
+	}
+	mod := t.seqModulus()
+	step := ((t.Seq-prev.Seq)%mod + mod) % mod
+	if step == 0 {
+		// Repeated sequence: a duplicate, not a full wrap of lost frames.
+		return 0, true
+	}
+	return step - 1, true
+}
+
+// DigitalStates reports, for each of the 8 digital channels in Bits, whether
+// it is in its active state. sense is the station's BITS.nnnnnnnn pattern
+// (Parsed.TBITS): a channel is active when its bit equals the sense bit, so a
+// '0' sense marks an inverted channel ("door open = 0"). An empty sense
+// means all channels are active-high. ok is false when Bits or sense is not
+// 8 binary digits.
+func (t TelemetryData) DigitalStates(sense string) (states [8]bool, ok bool) {
+	if sense == "" {
+		sense = "11111111"
+	}
+	if len(t.Bits) != 8 || len(sense) != 8 || !isBinaryString(t.Bits) || !isBinaryString(sense) {
+		return states, false
+	}
+	for i := range states {
+		states[i] = t.Bits[i] == sense[i]
+	}
+	return states, true
 }
 
 // parseCommentTelemetry parses comment telemetry from APRS packet
 func (p *Parsed) parseCommentTelemetry(text string) string {
+	if strings.Count(text, "|") < 2 {
+		return text
+	}
+
 	pattern := `^(.*?)\|([!-{]{4,14})\|(.*)$`
-	re := regexp.MustCompile(pattern)
+	re := aprsutils.CompiledRegexps.MustCompile(pattern)
 	matches := re.FindStringSubmatch(text)
 
 	if len(matches) >= 4 && len(matches[2])%2 == 0 {
-		text, telemetry, post := matches[1], matches[2], matches[3]
-		text += post
+		telemetry := matches[2]
+		text = matches[1] + matches[3]
 
 		temp := make([]int, 7)
 		for i := 0; i < 7 && i*2+2 <= utils.StringLen(telemetry); i++ {
@@ -34,8 +114,10 @@ func (p *Parsed) parseCommentTelemetry(text string) string {
 		}
 
 		telemetryData := TelemetryData{
-			Seq:  temp[0],
-			Vals: temp[1:6],
+			RawSeq: string([]rune(telemetry)[0:2]),
+			Seq:    temp[0],
+			Vals:   temp[1:6],
+			Base91: true,
 		}
 
 		if temp[6] != 0 {
@@ -60,7 +142,7 @@ func (p *Parsed) parseCommentTelemetry(text string) string {
 // parseTelemetryConfig parses telemetry config from APRS packet
 func (p *Parsed) parseTelemetryConfig(body string) (string, error) {
 	pattern := `^(PARM|UNIT|EQNS|BITS)\.(.*)$`
-	re := regexp.MustCompile(pattern)
+	re := aprsutils.CompiledRegexps.MustCompile(pattern)
 	matches := re.FindStringSubmatch(body)
 
 	if len(matches) >= 3 {
@@ -117,7 +199,7 @@ func (p *Parsed) parseTelemetryConfig(body string) (string, error) {
 					continue
 				}
 
-				if !regexp.MustCompile(`^[-]?\d*\.?\d+$`).MatchString(val) {
+				if !aprsutils.CompiledRegexps.MustCompile(`^[-]?\d*\.?\d+$`).MatchString(val) {
 					return body, errors.New(
 						strings.Join([]string{
 							"value at ", strconv.Itoa(idx + 1), " is not a number in ", form,
@@ -146,7 +228,7 @@ func (p *Parsed) parseTelemetryConfig(body string) (string, error) {
 
 		case "BITS":
 			pattern := `^([01]{8}),(.{0,23})$`
-			re := regexp.MustCompile(pattern)
+			re := aprsutils.CompiledRegexps.MustCompile(pattern)
 			matches := re.FindStringSubmatch(strings.TrimRight(body, " "))
 			if len(matches) < 3 {
 				return body, errors.New(
@@ -1,10 +1,15 @@
 package parser
 
-// parseThirdParty parses third-party data from APRS packet
+// parseThirdParty parses third-party data from APRS packet. The inner packet
+// is decoded with the same options as the outer one.
 func (p *Parsed) parseThirdParty(body string) error {
 	p.Format = "thirdparty"
 
-	parsed, err := Parse(body)
+	limits, err := p.limits.child()
+	if err != nil {
+		return err
+	}
+	parsed, err := Parse(body, withConfig(p.conf), withLimitState(limits))
 	if err != nil {
 		return err
 	}
@@ -13,3 +18,13 @@ func (p *Parsed) parseThirdParty(body string) error {
 
 	return nil
 }
+
+// withConfig makes a nested Parse start from the configuration of its
+// parent.
+func withConfig(conf *config) Option {
+	return func(p *config) {
+		if conf != nil {
+			*p = *conf
+		}
+	}
+}
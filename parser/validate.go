@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/APRSCN/aprsutils/utils"
+)
+
+// Practical packet size limits.
+const (
+	// MaxRFInfoLength is the largest information field an AX.25 UI frame can
+	// carry on RF.
+	MaxRFInfoLength = 256
+	// MaxISLineLength is the longest packet line (excluding the CRLF
+	// terminator) APRS-IS servers accept.
+	MaxISLineLength = 510
+)
+
+// Packet validation errors, returned by ValidatePacket so callers can tell an
+// RF-only size problem from a packet that is unusable everywhere.
+var (
+	ErrPacketTooLongRF    = errors.New("packet information field is too long for RF")
+	ErrPacketTooLongIS    = errors.New("packet is too long for APRS-IS")
+	ErrPacketLineBreak    = errors.New("packet contains a CR or LF")
+	ErrPacketControlChar  = errors.New("packet contains a control character")
+	ErrPacketInfoMissing  = errors.New("packet has no information field")
+	ErrPacketHeaderFormat = errors.New("packet header is malformed")
+)
+
+// ValidatePacket checks that a TNC2-format packet built for transmission fits
+// APRS length and character limits: the header must parse, the information
+// field must be present and free of line breaks and stray control characters
+// (the 0x1c-0x1f and DEL bytes Mic-E data uses, and TAB, are allowed), and
+// the packet must not exceed MaxISLineLength (ErrPacketTooLongIS) or carry an
+// information field longer than MaxRFInfoLength (ErrPacketTooLongRF).
+//
+// A single trailing CRLF is tolerated; any other CR/LF is rejected.
+func ValidatePacket(packet string) error {
+	packet = strings.TrimSuffix(packet, "\r\n")
+
+	if len(packet) > MaxISLineLength {
+		return ErrPacketTooLongIS
+	}
+
+	for _, r := range packet {
+		if r == '\r' || r == '\n' {
+			return ErrPacketLineBreak
+		}
+		if isStrayControl(r) {
+			return ErrPacketControlChar
+		}
+	}
+
+	head, body, ok := utils.SplitOnce(packet, ":")
+	if !ok || body == "" {
+		return ErrPacketInfoMissing
+	}
+
+	p := new(Parsed)
+	if err := p.parseHeader(head, &config{}); err != nil {
+		return errors.Join(ErrPacketHeaderFormat, err)
+	}
+
+	if len(body) > MaxRFInfoLength {
+		return ErrPacketTooLongRF
+	}
+
+	return nil
+}
@@ -5,8 +5,7 @@ import (
 	"strconv"
 	"strings"
 
-	"go.gh.ink/regexp"
-
+	"github.com/APRSCN/aprsutils"
 	"github.com/APRSCN/aprsutils/utils"
 )
 
@@ -32,6 +31,13 @@ var keyMap = map[string]string{
 	"#": "rainRaw",
 }
 
+// weatherRanges bounds the decoded values that have a physical limit. Values
+// outside are still stored, as sent, but raise a warning.
+var weatherRanges = map[string][2]float64{
+	"humidity":      {0, 100},
+	"windDirection": {0, 360},
+}
+
 var valMap = map[string]func(string) float64{
 	"g": func(x string) float64 {
 		val, _ := strconv.Atoi(x)
@@ -92,19 +98,25 @@ var valMap = map[string]func(string) float64{
 
 // parseWeatherData parses weather data from APRS packet
 func (p *Parsed) parseWeatherData(body string) string {
-	re1 := regexp.MustCompile(`^([0-9]{3})/([0-9]{3})`)
+	re1 := aprsutils.CompiledRegexps.MustCompile(`^([0-9]{3})/([0-9]{3})`)
 	body = re1.ReplaceAllString(body, "c${1}s${2}")
 	body = strings.Replace(body, "s", "S", 1)
 
-	re2 := regexp.MustCompile(`^([cSgtrpPlLs#][0-9\-. ]{3}|h[0-9. ]{2}|b[0-9. ]{5})+`)
+	re2 := aprsutils.CompiledRegexps.MustCompile(`^([cSgtrpPlLs#][0-9\-. ]{3}|h[0-9. ]{2}|b[0-9. ]{5})+`)
 
 	if dataMatch := re2.FindString(body); dataMatch != "" {
 		data := dataMatch
 		body = string([]rune(body)[utils.StringLen(data):])
 
-		re3 := regexp.MustCompile(`([cSgtrpPlLs#]\d{3}|t-\d{2}|h\d{2}|b\d{5}|s\.\d{2}|s\d\.\d)`)
+		re3 := aprsutils.CompiledRegexps.MustCompile(`([cSgtrpPlLs#]\d{3}|t-\d{2}|h\d{2}|b\d{5}|s\.\d{2}|s\d\.\d)`)
 		matches := re3.FindAllString(data, -1)
 
+		// Humidity is h00-h99 (h00 = 100%). A third digit, as in "h100",
+		// ends the weather data; flag it rather than guess.
+		if n := len(matches); n > 0 && matches[n-1][0] == 'h' && body != "" && body[0] >= '0' && body[0] <= '9' {
+			p.warn("humidity", "humidity "+matches[n-1][1:]+body[:1]+" has three digits, read as "+matches[n-1][1:])
+		}
+
 		// Initialise the map once; each match contributes a distinct field, so
 		// it must not be reset inside the loop (which would discard all but the
 		// last field).
@@ -122,7 +134,11 @@ func (p *Parsed) parseWeatherData(body string) string {
 
 			if keyFunc, ok := valMap[keyChar]; ok {
 				if keyName, ok := keyMap[keyChar]; ok {
-					p.Weather[keyName] = keyFunc(valueStr)
+					val := keyFunc(valueStr)
+					if r, ok := weatherRanges[keyName]; ok && (val < r[0] || val > r[1]) {
+						p.warn(keyName, keyName+" "+strconv.FormatFloat(val, 'f', -1, 64)+" out of range")
+					}
+					p.Weather[keyName] = val
 				}
 			}
 		}
@@ -133,16 +149,91 @@ func (p *Parsed) parseWeatherData(body string) string {
 
 // parseWeather parses weather data from APRS packet
 func (p *Parsed) parseWeather(body string) (string, error) {
-	re := regexp.MustCompile(`^(\d{8})c[. \d]{3}s[. \d]{3}g[. \d]{3}t[. \d]{3}`)
+	// The temperature may be negative, "t-05", and any field may be blank or
+	// dotted ("t..."): not measured.
+	re := aprsutils.CompiledRegexps.MustCompile(`^(\d{8})c[. \d]{3}s[. \d]{3}g[. \d]{3}t(?:-\d{2}|[. \d]{3})`)
 	match := re.FindStringSubmatch(body)
 
 	if match == nil {
 		return "", errors.New("invalid positionless weather report format")
 	}
 
+	// MDHM: month, day, hour and minute (UTC) of the report
+	p.parseMDHMTimeStamp(match[1])
+
 	comment := p.parseWeatherData(string([]rune(body)[8:]))
 
 	p.Comment = strings.Trim(comment, " ")
 
 	return "", nil
 }
+
+// WeatherField is a bitmask of the fields present in a WeatherReport.
+type WeatherField uint32
+
+const (
+	WeatherWindDirection     WeatherField = 1 << iota // c / course
+	WeatherWindSpeed                                  // s / speed
+	WeatherWindGust                                   // g
+	WeatherTemperature                                // t
+	WeatherRain1h                                     // r
+	WeatherRain24h                                    // p
+	WeatherRainSinceMidnight                          // P
+	WeatherHumidity                                   // h
+	WeatherPressure                                   // b
+	WeatherLuminosity                                 // l / L
+	WeatherSnow                                       // s (snowfall)
+	WeatherRainRaw                                    // #
+)
+
+// WeatherReport is the typed form of Parsed.Weather, in SI-style units.
+// Present records which fields the packet actually carried.
+type WeatherReport struct {
+	Present             WeatherField
+	WindDirectionDeg    float64 // degrees
+	WindSpeedMs         float64 // m/s (sustained)
+	WindGustMs          float64 // m/s
+	TemperatureC        float64 // degrees Celsius
+	Rain1hMm            float64 // mm in the last hour
+	Rain24hMm           float64 // mm in the last 24 hours
+	RainSinceMidnightMm float64 // mm since local midnight
+	Humidity            float64 // percent
+	PressureHpa         float64 // hPa (mbar)
+	LuminosityWm2       float64 // W/m²
+	SnowMm              float64 // mm in the last 24 hours
+	RainRaw             float64 // raw rain counter
+}
+
+// Has reports whether the given field is present.
+func (w *WeatherReport) Has(f WeatherField) bool { return w.Present&f != 0 }
+
+// weatherFields binds each Parsed.Weather key to its WeatherReport field.
+var weatherFields = map[string]struct {
+	field WeatherField
+	value func(*WeatherReport) *float64
+}{
+	"windDirection":     {WeatherWindDirection, func(w *WeatherReport) *float64 { return &w.WindDirectionDeg }},
+	"windSpeed":         {WeatherWindSpeed, func(w *WeatherReport) *float64 { return &w.WindSpeedMs }},
+	"windGust":          {WeatherWindGust, func(w *WeatherReport) *float64 { return &w.WindGustMs }},
+	"temperature":       {WeatherTemperature, func(w *WeatherReport) *float64 { return &w.TemperatureC }},
+	"rain1h":            {WeatherRain1h, func(w *WeatherReport) *float64 { return &w.Rain1hMm }},
+	"rain24h":           {WeatherRain24h, func(w *WeatherReport) *float64 { return &w.Rain24hMm }},
+	"rainSinceMidnight": {WeatherRainSinceMidnight, func(w *WeatherReport) *float64 { return &w.RainSinceMidnightMm }},
+	"humidity":          {WeatherHumidity, func(w *WeatherReport) *float64 { return &w.Humidity }},
+	"pressure":          {WeatherPressure, func(w *WeatherReport) *float64 { return &w.PressureHpa }},
+	"luminosity":        {WeatherLuminosity, func(w *WeatherReport) *float64 { return &w.LuminosityWm2 }},
+	"snow":              {WeatherSnow, func(w *WeatherReport) *float64 { return &w.SnowMm }},
+	"rainRaw":           {WeatherRainRaw, func(w *WeatherReport) *float64 { return &w.RainRaw }},
+}
+
+// newWeatherReport converts the Weather map into a WeatherReport.
+func newWeatherReport(weather map[string]float64) *WeatherReport {
+	w := new(WeatherReport)
+	for key, val := range weather {
+		if f, ok := weatherFields[key]; ok {
+			*f.value(w) = val
+			w.Present |= f.field
+		}
+	}
+	return w
+}
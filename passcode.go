@@ -1,6 +1,7 @@
 package aprsutils
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/APRSCN/aprsutils/utils"
@@ -8,6 +9,10 @@ import (
 
 const key = 0x73e2 // This is the key for the data
 
+// ReadOnlyPasscode is the passcode sent by clients that only receive; the
+// server accepts the login as unverified.
+const ReadOnlyPasscode = "-1"
+
 // Passcode calculates passcode of the callsign
 func Passcode(callsign string) int {
 	// Trim SSID
@@ -31,3 +36,9 @@ func Passcode(callsign string) int {
 
 	return hash & 0x7fff
 }
+
+// PasscodeString returns the passcode of the callsign as the decimal string
+// used in an APRS-IS login line.
+func PasscodeString(callsign string) string {
+	return strconv.Itoa(Passcode(callsign))
+}
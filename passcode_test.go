@@ -16,3 +16,12 @@ func TestPasscode(t *testing.T) {
 		t.Error("N0CALL-10 passcode mismatch")
 	}
 }
+
+func TestPasscodeString(t *testing.T) {
+	if got := PasscodeString("N0CALL"); got != "13023" {
+		t.Errorf("PasscodeString(N0CALL) = %q, want 13023", got)
+	}
+	if got := PasscodeString("N0CALL-10"); got != "13023" {
+		t.Errorf("PasscodeString(N0CALL-10) = %q, want 13023", got)
+	}
+}
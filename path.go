@@ -0,0 +1,131 @@
+package aprsutils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Recommended New-N path limits (http://www.aprs.org/fix14439.html): no
+// single WIDEn-N above WIDE2-2 and no more than three hops in total (e.g.
+// WIDE1-1,WIDE2-1 for a mobile).
+const (
+	MaxRecommendedHopsPerAlias = 2
+	MaxRecommendedHops         = 3
+)
+
+// newNAliasRe matches a WIDEn-N / TRACEn-N alias, capturing the alias, n and
+// the optional remaining count N.
+var newNAliasRe = CompiledRegexps.MustCompile(`^(WIDE|TRACE)([1-7])(?:-([0-7]))?$`)
+
+// PathWarnings inspects the RF digipeater path of a packet (the elements
+// after the destination, as in parser.Parsed.Path) and returns a
+// human-readable warning for each sign of a misconfigured or abusive path:
+//
+//   - a WIDEn-N asking for more than MaxRecommendedHopsPerAlias hops
+//   - more than MaxRecommendedHops hops requested over the whole path
+//   - an n-N alias whose remaining count exceeds its hop count
+//   - the deprecated RELAY, WIDE, TRACE and TRACEn-N aliases
+//   - the same alias requested more than once
+//
+// Elements from the first q-construct or TCPIP/TCPXX on are APRS-IS routing
+// and are ignored. A clean path yields nil.
+func PathWarnings(path []string) []string {
+	var warnings []string
+	var seen []string
+	total := 0
+	aliases := 0
+
+	for _, element := range path {
+		hop := strings.ToUpper(strings.TrimSuffix(element, "*"))
+		if (len(hop) == 3 && hop[0] == 'Q') || hop == "TCPIP" || hop == "TCPXX" {
+			break
+		}
+
+		switch hop {
+		case "RELAY", "WIDE", "TRACE":
+			warnings = append(warnings, fmt.Sprintf("%s: deprecated alias, use WIDEn-N", element))
+			continue
+		}
+
+		m := newNAliasRe.FindStringSubmatch(hop)
+		if m == nil {
+			continue
+		}
+		for _, prev := range seen {
+			if SameStation(prev, hop, false) {
+				warnings = append(warnings, fmt.Sprintf("%s: duplicate path element", element))
+				break
+			}
+		}
+		seen = append(seen, hop)
+
+		if m[1] == "TRACE" {
+			warnings = append(warnings, fmt.Sprintf("%s: deprecated alias, use WIDEn-N", element))
+		}
+
+		n, _ := strconv.Atoi(m[2])
+		if m[3] != "" {
+			if remaining, _ := strconv.Atoi(m[3]); remaining > n {
+				warnings = append(warnings, fmt.Sprintf("%s: remaining hops exceed requested hops", element))
+			}
+		}
+		if n > MaxRecommendedHopsPerAlias {
+			warnings = append(warnings, fmt.Sprintf("%s: requests %d hops, at most %d recommended", element, n, MaxRecommendedHopsPerAlias))
+		}
+		total += n
+		aliases++
+	}
+
+	// A single oversized alias is already reported above.
+	if aliases > 1 && total > MaxRecommendedHops {
+		warnings = append(warnings, fmt.Sprintf("path requests %d hops, at most %d recommended", total, MaxRecommendedHops))
+	}
+
+	return warnings
+}
+
+// PathDiff describes how the paths of two copies of the same packet differ.
+type PathDiff struct {
+	Common     []string // leading elements shared by both paths
+	Divergence int      // index of the first differing element, -1 if the paths are equal
+	QA, QB     string   // q-construct of each path, "" if none
+	IgateA     string   // injecting igate of path a (the element after its q-construct), "" if none
+	IgateB     string   // injecting igate of path b, "" if none
+}
+
+// ComparePaths compares the paths of two copies of a packet, as in
+// parser.Parsed.Path, for diagnosing duplicates that reached APRS-IS through
+// different igates. Elements are compared case-insensitively, including any
+// "*" used-hop marker. A path that is a prefix of the other diverges at the
+// end of the shorter one.
+func ComparePaths(a, b []string) PathDiff {
+	d := PathDiff{Divergence: -1}
+
+	n := 0
+	for n < len(a) && n < len(b) && strings.EqualFold(a[n], b[n]) {
+		n++
+	}
+	d.Common = append([]string(nil), a[:n]...)
+	if n < len(a) || n < len(b) {
+		d.Divergence = n
+	}
+
+	d.QA, d.IgateA = injectingIgate(a)
+	d.QB, d.IgateB = injectingIgate(b)
+	return d
+}
+
+// injectingIgate returns the first qA? construct in path and the element
+// that follows it.
+func injectingIgate(path []string) (q, igate string) {
+	for i, element := range path {
+		if len(element) == 3 && strings.EqualFold(element[:2], "qA") {
+			if i+1 < len(path) {
+				igate = path[i+1]
+			}
+			return element, igate
+		}
+	}
+	return "", ""
+}
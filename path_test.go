@@ -0,0 +1,66 @@
+package aprsutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathWarnings(t *testing.T) {
+	cases := []struct {
+		path []string
+		want []string
+	}{
+		{[]string{"WIDE1-1", "WIDE2-1"}, nil},
+		{[]string{"N0DIG*", "WIDE2-1", "qAR", "IGATE"}, nil},
+		{[]string{"WIDE7-7"}, []string{"WIDE7-7: requests 7 hops, at most 2 recommended"}},
+		{[]string{"RELAY", "WIDE"}, []string{
+			"RELAY: deprecated alias, use WIDEn-N",
+			"WIDE: deprecated alias, use WIDEn-N",
+		}},
+		{[]string{"WIDE2-2", "WIDE2-2"}, []string{
+			"WIDE2-2: duplicate path element",
+			"path requests 4 hops, at most 3 recommended",
+		}},
+		// A bare alias and its "-0" form are the same element.
+		{[]string{"WIDE2", "wide2-0"}, []string{
+			"wide2-0: duplicate path element",
+			"path requests 4 hops, at most 3 recommended",
+		}},
+		{[]string{"TRACE3-5"}, []string{
+			"TRACE3-5: deprecated alias, use WIDEn-N",
+			"TRACE3-5: remaining hops exceed requested hops",
+			"TRACE3-5: requests 3 hops, at most 2 recommended",
+		}},
+		// Aliases after the q-construct belong to APRS-IS and are ignored.
+		{[]string{"WIDE1-1", "qAC", "WIDE7-7"}, nil},
+	}
+	for _, c := range cases {
+		if got := PathWarnings(c.path); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("PathWarnings(%v) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestComparePaths(t *testing.T) {
+	a := []string{"N0DIG*", "WIDE2-1", "qAR", "IGATE1"}
+	b := []string{"N0DIG*", "WIDE2-1", "qAO", "IGATE2-10"}
+	d := ComparePaths(a, b)
+	if !reflect.DeepEqual(d.Common, []string{"N0DIG*", "WIDE2-1"}) || d.Divergence != 2 {
+		t.Errorf("common = %v, divergence = %d", d.Common, d.Divergence)
+	}
+	if d.QA != "qAR" || d.IgateA != "IGATE1" || d.QB != "qAO" || d.IgateB != "IGATE2-10" {
+		t.Errorf("igates = %s %s / %s %s", d.QA, d.IgateA, d.QB, d.IgateB)
+	}
+
+	// Identical paths have no divergence point.
+	if d := ComparePaths(a, a); d.Divergence != -1 || len(d.Common) != len(a) {
+		t.Errorf("identical: %+v", d)
+	}
+
+	// A heard-direct copy diverges at the first element and has no igate
+	// if it never reached APRS-IS.
+	d = ComparePaths([]string{"WIDE1-1"}, []string{"N0DIG*", "qAR", "IGATE1"})
+	if d.Divergence != 0 || len(d.Common) != 0 || d.IgateA != "" || d.IgateB != "IGATE1" {
+		t.Errorf("direct: %+v", d)
+	}
+}
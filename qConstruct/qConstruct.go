@@ -58,12 +58,12 @@ type QResult struct {
 	IsLoop     bool
 }
 
-// QConstruct processes QConstruct
+// QConstruct processes QConstruct. The incoming path is normalized first
+// (see normalizePath), so a hand-built Parsed behaves like a parsed one.
 func QConstruct(p parser.Parsed, config *QConfig) (QResult, error) {
 	result := &QResult{
-		Path: make([]string, len(p.Path)),
+		Path: normalizePath(p.Path),
 	}
-	copy(result.Path, p.Path)
 
 	// Apply initial processing for all packets
 	result.applyInitialProcessing(p, config)
@@ -93,6 +93,20 @@ func QConstruct(p parser.Parsed, config *QConfig) (QResult, error) {
 	return *result, nil
 }
 
+// normalizePath returns a copy of path with whitespace trimmed from every
+// element and empty elements dropped, as parseHeader does. Case is kept:
+// the duplicate-callsign loop check is case-sensitive (like aprsc), and the
+// other comparisons already ignore case.
+func normalizePath(path []string) []string {
+	out := make([]string, 0, len(path))
+	for _, element := range path {
+		if element = strings.TrimSpace(element); element != "" {
+			out = append(out, element)
+		}
+	}
+	return out
+}
+
 // applyInitialProcessing inits to processing packet
 func (r *QResult) applyInitialProcessing(p parser.Parsed, config *QConfig) {
 	// Remove q construct if it's last in path with no call following
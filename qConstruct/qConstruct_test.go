@@ -161,3 +161,27 @@ func TestDisallowOtherQProtocols(t *testing.T) {
 		t.Error("packet with qZ should not be dropped when policy is off")
 	}
 }
+
+// TestQNormalizesPath feeds a hand-built path with padding, empty elements and
+// mixed case: it is trimmed and compacted, and loop detection still works.
+func TestQNormalizesPath(t *testing.T) {
+	p := parser.Parsed{
+		From: "SRCCALL",
+		To:   "DST",
+		Path: []string{" DIGI1* ", "", "qAR", "  ", " igate\t"},
+	}
+	res, err := QConstruct(p, verifiedCfg("SRCCALL"))
+	if err != nil {
+		t.Fatalf("QConstruct: %v", err)
+	}
+	if got, want := res.GetPathString(), "DIGI1*,qAR,igate"; got != want {
+		t.Errorf("path = %q, want %q", got, want)
+	}
+
+	// The padded server login is still recognised as a loop.
+	p.Path = []string{"DIGI1*", "qAR", " testing "}
+	res, _ = QConstruct(p, verifiedCfg("SRCCALL"))
+	if !res.ShouldDrop || !res.IsLoop {
+		t.Errorf("padded server login: drop=%v loop=%v, want a loop", res.ShouldDrop, res.IsLoop)
+	}
+}
@@ -0,0 +1,96 @@
+package aprsutils
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Directed query types (aprs101.pdf ch. 15), sent as a message whose text is
+// "?APRSx".
+const (
+	QueryPosition = "APRSP" // respond with a position report
+	QueryVersion  = "APRSV" // respond with the software version
+	QueryDirect   = "APRSD" // respond with the stations heard directly
+)
+
+// BuildQueryResponse builds the packet with which myCall answers a directed
+// query of type qtype (with or without the leading "?") sent by toCall:
+//
+//   - QueryPosition: data[0] is myCall's position information field (e.g.
+//     "!4903.50N/07201.75W-"); the reply is that position report.
+//   - QueryVersion: the reply is a message to toCall carrying data[0], or
+//     "<Name> <Version>" of this library when omitted.
+//   - QueryDirect: the reply is a message to toCall listing the callsigns in
+//     data as "Directs= CALL1 CALL2 ...".
+//
+// The packet has no path; add one before transmitting on RF.
+func BuildQueryResponse(myCall, toCall string, qtype string, data ...string) (string, error) {
+	if !ValidateCallsign(myCall) || !ValidateCallsign(toCall) {
+		return "", errors.New("invalid callsign")
+	}
+
+	switch strings.ToUpper(strings.Trim(qtype, "?")) {
+	case QueryPosition:
+		if len(data) == 0 || data[0] == "" {
+			return "", errors.New("position query response needs a position report")
+		}
+		if !strings.ContainsAny(data[0][:1], "!=/@") {
+			return "", errors.New("position report must start with a position data type")
+		}
+		return buildPacket(myCall, DefaultToCall, nil, data[0]), nil
+	case QueryVersion:
+		version := Name + " " + Version
+		if len(data) > 0 && data[0] != "" {
+			version = data[0]
+		}
+		return buildPacket(myCall, DefaultToCall, nil, messageInfo(toCall, version)), nil
+	case QueryDirect:
+		text := strings.TrimSpace("Directs= " + strings.Join(data, " "))
+		return buildPacket(myCall, DefaultToCall, nil, messageInfo(toCall, text)), nil
+	default:
+		return "", errors.New("unsupported query type")
+	}
+}
+
+// milesToKm converts the statute miles of a query footprint to kilometres.
+const milesToKm = 1.609344
+
+// rangeQueryRe matches a general query with a target footprint (aprs101.pdf
+// ch. 15), e.g. "?APRS? 34.02,-117.15,0200": latitude and longitude in
+// decimal degrees and a radius in miles.
+var rangeQueryRe = CompiledRegexps.MustCompile(`^\??[A-Z]+\?\s*(-?\d{1,2}(?:\.\d*)?),\s*(-?\d{1,3}(?:\.\d*)?),\s*(\d{1,4})\s*$`)
+
+// ParseRangeQuery parses the footprint of a general range query such as
+// "?APRS? 34.02,-117.15,0200" (the leading "?" may be omitted, as in
+// parser.Parsed.Body). ok is false if body has no valid footprint; otherwise
+// the centre is returned in decimal degrees and the radius in kilometres.
+func ParseRangeQuery(body string) (lat, lon, radiusKm float64, ok bool) {
+	m := rangeQueryRe.FindStringSubmatch(strings.TrimSpace(body))
+	if m == nil {
+		return 0, 0, 0, false
+	}
+	lat, _ = strconv.ParseFloat(m[1], 64)
+	lon, _ = strconv.ParseFloat(m[2], 64)
+	miles, _ := strconv.Atoi(m[3])
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 || miles == 0 {
+		return 0, 0, 0, false
+	}
+	return lat, lon, float64(miles) * milesToKm, true
+}
+
+// StationsInRange returns, sorted, the callsigns of the stations whose last
+// known position lies within radiusKm of (lat, lon) — the stations a range
+// query parsed by ParseRangeQuery asks about.
+func StationsInRange(stations map[string]LatLon, lat, lon, radiusKm float64) []string {
+	center := LatLon{Lat: lat, Lon: lon}
+	var calls []string
+	for call, pos := range stations {
+		if WithinRadius(center, pos, radiusKm) {
+			calls = append(calls, call)
+		}
+	}
+	slices.Sort(calls)
+	return calls
+}
@@ -0,0 +1,64 @@
+package aprsutils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildQueryResponse(t *testing.T) {
+	cases := []struct {
+		qtype string
+		data  []string
+		want  string
+	}{
+		{"?APRSP", []string{"!4903.50N/07201.75W-Home"}, "N0CALL>APRS:!4903.50N/07201.75W-Home"},
+		{"APRSV", []string{"myapp 2.0"}, "N0CALL>APRS::K1ABC-7  :myapp 2.0"},
+		{"?APRSV", nil, "N0CALL>APRS::K1ABC-7  :" + Name + " " + Version},
+		{"?APRSD", []string{"W1AW", "K2XYZ-9"}, "N0CALL>APRS::K1ABC-7  :Directs= W1AW K2XYZ-9"},
+	}
+	for _, c := range cases {
+		got, err := BuildQueryResponse("N0CALL", "K1ABC-7", c.qtype, c.data...)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.qtype, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: got %q, want %q", c.qtype, got, c.want)
+		}
+	}
+
+	if _, err := BuildQueryResponse("N0CALL", "K1ABC", "?APRSP"); err == nil {
+		t.Error("position response without a report should fail")
+	}
+	if _, err := BuildQueryResponse("N0CALL", "K1ABC", "?WX?"); err == nil {
+		t.Error("unsupported query type should fail")
+	}
+}
+
+func TestParseRangeQuery(t *testing.T) {
+	lat, lon, km, ok := ParseRangeQuery("?APRS? 34.02,-117.15,0200")
+	if !ok || lat != 34.02 || lon != -117.15 || km != 200*milesToKm {
+		t.Errorf("ParseRangeQuery = %v, %v, %v, %v", lat, lon, km, ok)
+	}
+	if _, _, _, ok := ParseRangeQuery("APRS? 34.02,-117.15,0200"); !ok {
+		t.Error("footprint without the leading '?' should parse")
+	}
+	for _, body := range []string{"?APRS?", "?APRS? 34.02,-117.15", "?APRS? 95.0,10.0,0100", "?APRS? 34.02,-117.15,0000"} {
+		if _, _, _, ok := ParseRangeQuery(body); ok {
+			t.Errorf("ParseRangeQuery(%q) should fail", body)
+		}
+	}
+
+	known := map[string]LatLon{
+		"N6NEAR":  {34.10, -117.20}, // ~10 km
+		"K6MID-9": {35.00, -117.00}, // ~110 km
+		"W1FAR":   {42.36, -71.06},  // Boston
+	}
+	got := StationsInRange(known, lat, lon, km)
+	if !slices.Equal(got, []string{"K6MID-9", "N6NEAR"}) {
+		t.Errorf("StationsInRange = %v", got)
+	}
+	if got := StationsInRange(known, lat, lon, 50); !slices.Equal(got, []string{"N6NEAR"}) {
+		t.Errorf("StationsInRange(50 km) = %v", got)
+	}
+}
@@ -0,0 +1,156 @@
+package aprsutils
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.gh.ink/regexp"
+)
+
+// Regexp is a compiled regular expression handed out by a RegexpCache. It
+// behaves like *regexp.Regexp; the matching methods used by the parser also
+// record usage counts while statistics are enabled on the owning cache.
+type Regexp struct {
+	*regexp.Regexp
+	cache     *RegexpCache
+	consulted atomic.Uint64
+	matched   atomic.Uint64
+}
+
+// RegexpStats holds the usage counters of a single cached pattern.
+type RegexpStats struct {
+	Consulted uint64 // times the pattern was evaluated
+	Matched   uint64 // times the evaluation found a match
+}
+
+// RegexpCache compiles each pattern once and shares it between callers. It is
+// safe for concurrent use.
+type RegexpCache struct {
+	mu      sync.RWMutex
+	entries map[string]*Regexp
+	stats   atomic.Bool
+}
+
+// CompiledRegexps is the cache used by the library's own parsers.
+var CompiledRegexps = NewRegexpCache()
+
+// NewRegexpCache creates an empty cache
+func NewRegexpCache() *RegexpCache {
+	return &RegexpCache{entries: make(map[string]*Regexp)}
+}
+
+// MustCompile returns the cached compiled form of pattern, compiling it on
+// first use. Like regexp.MustCompile it panics on an invalid pattern.
+func (c *RegexpCache) MustCompile(pattern string) *Regexp {
+	c.mu.RLock()
+	re, ok := c.entries[pattern]
+	c.mu.RUnlock()
+	if ok {
+		return re
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if re, ok = c.entries[pattern]; ok {
+		return re
+	}
+	re = &Regexp{Regexp: regexp.MustCompile(pattern), cache: c}
+	c.entries[pattern] = re
+	return re
+}
+
+// Len returns the number of cached patterns.
+func (c *RegexpCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Clear drops every cached pattern, releasing patterns that were compiled
+// dynamically. Later MustCompile calls compile afresh, so Regexp values
+// obtained before Clear stay usable but are no longer shared or counted in
+// Stats. It is not safe to clear a cache while parses using it are in
+// progress: call it only when no goroutine is parsing.
+func (c *RegexpCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*Regexp)
+}
+
+// EnableStats turns usage counting on or off. Counting is off by default so
+// production parsing pays no bookkeeping cost.
+func (c *RegexpCache) EnableStats(on bool) {
+	c.stats.Store(on)
+}
+
+// Stats returns the usage counters of every cached pattern, keyed by pattern
+// string. Counters only advance while statistics are enabled.
+func (c *RegexpCache) Stats() map[string]RegexpStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make(map[string]RegexpStats, len(c.entries))
+	for pattern, re := range c.entries {
+		out[pattern] = RegexpStats{
+			Consulted: re.consulted.Load(),
+			Matched:   re.matched.Load(),
+		}
+	}
+	return out
+}
+
+// ResetStats zeroes the usage counters of every cached pattern, e.g. before
+// profiling a single Parse call.
+func (c *RegexpCache) ResetStats() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, re := range c.entries {
+		re.consulted.Store(0)
+		re.matched.Store(0)
+	}
+}
+
+// record counts one evaluation of re when statistics are enabled.
+func (re *Regexp) record(matched bool) {
+	if re.cache == nil || !re.cache.stats.Load() {
+		return
+	}
+	re.consulted.Add(1)
+	if matched {
+		re.matched.Add(1)
+	}
+}
+
+// MatchString reports whether s contains any match of re
+func (re *Regexp) MatchString(s string) bool {
+	ok := re.Regexp.MatchString(s)
+	re.record(ok)
+	return ok
+}
+
+// FindString returns the leftmost match of re in s
+func (re *Regexp) FindString(s string) string {
+	m := re.Regexp.FindString(s)
+	re.record(m != "")
+	return m
+}
+
+// FindStringSubmatch returns the leftmost match of re in s and its submatches
+func (re *Regexp) FindStringSubmatch(s string) []string {
+	m := re.Regexp.FindStringSubmatch(s)
+	re.record(m != nil)
+	return m
+}
+
+// FindAllString returns up to n successive matches of re in s
+func (re *Regexp) FindAllString(s string, n int) []string {
+	m := re.Regexp.FindAllString(s, n)
+	re.record(m != nil)
+	return m
+}
+
+// ReplaceAllString replaces the matches of re in src with repl
+func (re *Regexp) ReplaceAllString(src, repl string) string {
+	out := re.Regexp.ReplaceAllString(src, repl)
+	re.record(out != src)
+	return out
+}
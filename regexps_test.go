@@ -0,0 +1,57 @@
+package aprsutils
+
+import "testing"
+
+func TestRegexpCacheStats(t *testing.T) {
+	c := NewRegexpCache()
+	re := c.MustCompile(`^N0`)
+	if c.MustCompile(`^N0`) != re {
+		t.Fatal("MustCompile did not return the cached Regexp")
+	}
+
+	// Disabled by default: nothing is counted.
+	re.MatchString("N0CALL")
+	if s := c.Stats()[`^N0`]; s.Consulted != 0 {
+		t.Errorf("stats counted while disabled: %+v", s)
+	}
+
+	c.EnableStats(true)
+	re.MatchString("N0CALL")
+	re.MatchString("K1ABC")
+	re.FindStringSubmatch("N0XYZ")
+	if s := c.Stats()[`^N0`]; s.Consulted != 3 || s.Matched != 2 {
+		t.Errorf("Stats = %+v, want 3 consulted / 2 matched", s)
+	}
+
+	c.ResetStats()
+	if s := c.Stats()[`^N0`]; s.Consulted != 0 || s.Matched != 0 {
+		t.Errorf("Stats after reset = %+v, want zero", s)
+	}
+}
+
+func TestRegexpCacheLenClear(t *testing.T) {
+	c := NewRegexpCache()
+	if c.Len() != 0 {
+		t.Fatalf("Len of new cache = %d, want 0", c.Len())
+	}
+	old := c.MustCompile(`^N0`)
+	c.MustCompile(`^N0`)
+	c.MustCompile(`^K1`)
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, want 2", c.Len())
+	}
+
+	c.Clear()
+	if c.Len() != 0 || len(c.Stats()) != 0 {
+		t.Errorf("after Clear: Len = %d, Stats = %v", c.Len(), c.Stats())
+	}
+	if !old.MatchString("N0CALL") {
+		t.Error("Regexp obtained before Clear stopped matching")
+	}
+	if c.MustCompile(`^N0`) == old {
+		t.Error("MustCompile after Clear returned the dropped Regexp")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len after recompile = %d, want 1", c.Len())
+	}
+}
@@ -0,0 +1,91 @@
+package aprsutils
+
+import (
+	"errors"
+	"strings"
+)
+
+// msgNoAlphabet is the digit set used for generated reply-ack message
+// numbers. Upper-case only, so numbers survive stations that fold case.
+const msgNoAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// NextMsgNo returns the two-character reply-ack message number following prev
+// (http://www.aprs.org/aprs11/replyacks.txt): "01" -> "02", "09" -> "0A",
+// "0Z" -> "10", and "ZZ" wraps to "00". An empty or unrecognized prev starts
+// the sequence at "01".
+func NextMsgNo(prev string) string {
+	prev = strings.ToUpper(prev)
+	if len(prev) != 2 {
+		return "01"
+	}
+	hi := strings.IndexByte(msgNoAlphabet, prev[0])
+	lo := strings.IndexByte(msgNoAlphabet, prev[1])
+	if hi < 0 || lo < 0 {
+		return "01"
+	}
+
+	base := len(msgNoAlphabet)
+	n := (hi*base + lo + 1) % (base * base)
+	return string([]byte{msgNoAlphabet[n/base], msgNoAlphabet[n%base]})
+}
+
+// ParseReplyAck extracts the reply-ack numbers from a message body (the text
+// after the addressee): "text{MM}AA" and "ackMM}AA"/"rejMM}AA" yield msgNo MM
+// and ackMsgNo AA, which is empty when the station has nothing to ack
+// ("text{MM}"). ok is false for bodies not in the reply-ack format, including
+// old-style "text{12345" numbers.
+func ParseReplyAck(body string) (msgNo, ackMsgNo string, ok bool) {
+	if m := CompiledRegexps.MustCompile(`^(ack|rej)([A-Za-z0-9]{2})}([A-Za-z0-9]{2})?$`).FindStringSubmatch(body); m != nil {
+		return m[2], m[3], true
+	}
+	if m := CompiledRegexps.MustCompile(`{([A-Za-z0-9]{2})}([A-Za-z0-9]{2})?$`).FindStringSubmatch(body); m != nil {
+		return m[1], m[2], true
+	}
+	return "", "", false
+}
+
+// msgNoRe matches a message number: up to five alphanumeric characters
+// (aprs101.pdf ch. 14).
+var msgNoRe = CompiledRegexps.MustCompile(`^[A-Za-z0-9]{1,5}$`)
+
+// replyAckNoRe matches a two-character reply-ack message number.
+var replyAckNoRe = CompiledRegexps.MustCompile(`^[A-Za-z0-9]{2}$`)
+
+// BuildAck builds the packet with which myCall acknowledges message msgNo
+// received from toCall, e.g. "N0CALL>APRS::KB2ICI-14:ack003". The addressee
+// is upper-cased and space-padded to nine characters; it must not be longer.
+// The packet has no path; add one before transmitting on RF.
+func BuildAck(myCall, toCall, msgNo string) (string, error) {
+	if err := validateAck(myCall, toCall); err != nil {
+		return "", err
+	}
+	if !msgNoRe.MatchString(msgNo) {
+		return "", errors.New("invalid message number")
+	}
+	return buildPacket(myCall, DefaultToCall, nil, messageInfo(toCall, "ack"+msgNo)), nil
+}
+
+// BuildReplyAck is BuildAck for a reply-ack message "text{MM}AA": it acks
+// msgNo MM and, when ackMsgNo is not empty, also acks ackMsgNo of the sender's
+// own messages in the same packet ("ackMM}AA"), as replyacks.txt describes.
+func BuildReplyAck(myCall, toCall, msgNo, ackMsgNo string) (string, error) {
+	if err := validateAck(myCall, toCall); err != nil {
+		return "", err
+	}
+	if !replyAckNoRe.MatchString(msgNo) || (ackMsgNo != "" && !replyAckNoRe.MatchString(ackMsgNo)) {
+		return "", errors.New("invalid reply-ack message number")
+	}
+	return buildPacket(myCall, DefaultToCall, nil, messageInfo(toCall, "ack"+msgNo+"}"+ackMsgNo)), nil
+}
+
+// validateAck checks the sender and addressee of an ack.
+func validateAck(myCall, toCall string) error {
+	if !ValidateCallsign(myCall) {
+		return errors.New("invalid callsign")
+	}
+	addressee := strings.TrimSpace(toCall)
+	if addressee == "" || len(addressee) > 9 || strings.ContainsAny(addressee, ":") {
+		return errors.New("addressee must be 1 to 9 characters without ':'")
+	}
+	return nil
+}
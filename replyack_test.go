@@ -0,0 +1,83 @@
+package aprsutils
+
+import "testing"
+
+func TestNextMsgNo(t *testing.T) {
+	cases := map[string]string{
+		"":    "01",
+		"01":  "02",
+		"09":  "0A",
+		"0Z":  "10",
+		"az":  "B0",
+		"ZZ":  "00",
+		"123": "01",
+	}
+	for prev, want := range cases {
+		if got := NextMsgNo(prev); got != want {
+			t.Errorf("NextMsgNo(%q) = %q, want %q", prev, got, want)
+		}
+	}
+}
+
+func TestParseReplyAck(t *testing.T) {
+	// Examples from replyacks.txt.
+	cases := []struct {
+		body, msgNo, ackMsgNo string
+		ok                    bool
+	}{
+		{"Hello{MM}", "MM", "", true},
+		{"Hello{MM}AA", "MM", "AA", true},
+		{"ackMM}AA", "MM", "AA", true},
+		{"rejMM}", "MM", "", true},
+		{"Hello{12345", "", "", false},
+		{"Hello", "", "", false},
+	}
+	for _, c := range cases {
+		msgNo, ackMsgNo, ok := ParseReplyAck(c.body)
+		if msgNo != c.msgNo || ackMsgNo != c.ackMsgNo || ok != c.ok {
+			t.Errorf("ParseReplyAck(%q) = %q, %q, %v; want %q, %q, %v",
+				c.body, msgNo, ackMsgNo, ok, c.msgNo, c.ackMsgNo, c.ok)
+		}
+	}
+}
+
+func TestBuildAck(t *testing.T) {
+	cases := []struct {
+		toCall, msgNo, want string
+	}{
+		// aprs101.pdf ch. 14 examples.
+		{"KB2ICI-14", "003", "N0CALL>APRS::KB2ICI-14:ack003"},
+		{"wu2z", "1", "N0CALL>APRS::WU2Z     :ack1"},
+		{"WU2Z", "AbC12", "N0CALL>APRS::WU2Z     :ackAbC12"},
+	}
+	for _, c := range cases {
+		got, err := BuildAck("N0CALL", c.toCall, c.msgNo)
+		if err != nil || got != c.want {
+			t.Errorf("BuildAck(%q, %q) = %q, %v; want %q", c.toCall, c.msgNo, got, err, c.want)
+		}
+	}
+
+	for _, bad := range [][2]string{
+		{"KB2ICI-140", "003"}, // addressee longer than nine characters
+		{"", "003"},
+		{"WU2Z", ""},
+		{"WU2Z", "123456"},
+		{"WU2Z", "1{2"},
+	} {
+		if got, err := BuildAck("N0CALL", bad[0], bad[1]); err == nil {
+			t.Errorf("BuildAck(%q, %q) = %q, want error", bad[0], bad[1], got)
+		}
+	}
+
+	got, err := BuildReplyAck("N0CALL", "WU2Z", "MM", "AA")
+	if err != nil || got != "N0CALL>APRS::WU2Z     :ackMM}AA" {
+		t.Errorf("BuildReplyAck = %q, %v", got, err)
+	}
+	got, err = BuildReplyAck("N0CALL", "WU2Z", "MM", "")
+	if err != nil || got != "N0CALL>APRS::WU2Z     :ackMM}" {
+		t.Errorf("BuildReplyAck without ackMsgNo = %q, %v", got, err)
+	}
+	if _, err := BuildReplyAck("N0CALL", "WU2Z", "003", ""); err == nil {
+		t.Error("BuildReplyAck accepted a three-character message number")
+	}
+}
@@ -0,0 +1,119 @@
+package aprsutils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SymbolEmoji maps an APRS symbol, keyed by table character ('/' primary or
+// '\' alternate) followed by the symbol code, to a Unicode glyph for display.
+// Overlaid alternate symbols share the '\' entry. The map is exported so
+// applications can add or override mappings.
+var SymbolEmoji = map[string]string{
+	// Primary table
+	"/!": "🚓",  // police station
+	"/$": "☎️", // phone
+	"/'": "🛩️", // small aircraft
+	"/-": "🏠",  // house (QTH)
+	"/;": "⛺",  // campground
+	"/<": "🏍️", // motorcycle
+	"/=": "🚆",  // railroad engine
+	"/>": "🚗",  // car
+	"/C": "🛶",  // canoe
+	"/E": "👁️", // eyeball (event)
+	"/F": "🚜",  // farm vehicle
+	"/H": "🏨",  // hotel
+	"/K": "🏫",  // school
+	"/O": "🎈",  // balloon
+	"/P": "🚓",  // police
+	"/R": "🚐",  // recreational vehicle
+	"/S": "🚀",  // space shuttle
+	"/U": "🚌",  // bus
+	"/X": "🚁",  // helicopter
+	"/Y": "⛵",  // yacht (sail)
+	"/[": "🚶",  // human / person
+	"/^": "✈️", // large aircraft
+	"/_": "🌦️", // weather station
+	"/a": "🚑",  // ambulance
+	"/b": "🚲",  // bicycle
+	"/d": "🚒",  // fire department
+	"/e": "🐎",  // horse
+	"/f": "🚒",  // fire truck
+	"/g": "🪂",  // glider
+	"/h": "🏥",  // hospital
+	"/j": "🚙",  // jeep
+	"/k": "🛻",  // truck
+	"/p": "🐕",  // rover (dog)
+	"/r": "📡",  // repeater
+	"/s": "🚤",  // ship (power boat)
+	"/u": "🚚",  // truck (18 wheeler)
+	"/v": "🚐",  // van
+	"/y": "📡",  // yagi at QTH
+
+	// Alternate table
+	"\\!": "🚨",  // emergency
+	"\\'": "💥",  // crash site
+	"\\*": "❄️", // snow
+	"\\:": "🌨️", // hail
+	"\\>": "🚗",  // car (overlay)
+	"\\@": "🌀",  // hurricane / tropical storm
+	"\\E": "💨",  // smoke
+	"\\F": "🌫️", // fog
+	"\\R": "🍴",  // restaurant
+	"\\T": "⛈️", // thunderstorm
+	"\\U": "☀️", // sunny
+	"\\^": "✈️", // aircraft (overlay)
+	"\\_": "🌦️", // weather site
+	"\\s": "🚢",  // ship (overlay)
+	"\\u": "🚚",  // truck (overlay)
+	"\\w": "🌊",  // flooding
+}
+
+// SymbolToEmoji returns a display glyph for the symbol code on the given
+// symbol table. table may be '/', '\' or an overlay character (which selects
+// the alternate table). ok is false when the symbol has no good mapping.
+func SymbolToEmoji(table, symbol string) (string, bool) {
+	if len(table) != 1 || len(symbol) != 1 {
+		return "", false
+	}
+	if table != "/" {
+		table = "\\"
+	}
+	emoji, ok := SymbolEmoji[table+symbol]
+	return emoji, ok
+}
+
+// ssidSymbols maps source SSIDs to the symbol of the station type the
+// de-facto SSID conventions (http://www.aprs.org/aprs11/SSIDs.txt) give them,
+// the same ones parser.WithStationRole uses. SSIDs for generic extra
+// stations, other networks, special activities and devices have none.
+var ssidSymbols = map[int]string{
+	0:  "/-",  // primary station, usually fixed: house
+	7:  "/[",  // HT or other human portable: person
+	8:  "/s",  // boat, sailboat, RV: ship
+	9:  "/>",  // primary mobile: car
+	10: "\\&", // internet, igate: gateway
+	11: "/O",  // balloon, aircraft, spacecraft: balloon
+	13: "/_",  // weather station
+	14: "/k",  // trucker: truck
+}
+
+// SymbolForSSID returns the symbol conventionally implied by a source SSID
+// ("9" or "-9"), following http://www.aprs.org/aprs11/SSIDs.txt (e.g. 9 =
+// car, 7 = HT). No SSID counts as 0, the primary station. This is a display
+// heuristic only: use it as a fallback when a packet carries no explicit
+// symbol. ok is false for SSIDs the convention gives no station type.
+func SymbolForSSID(ssid string) (table, symbol string, ok bool) {
+	n := 0
+	if ssid = strings.TrimPrefix(ssid, "-"); ssid != "" {
+		var err error
+		if n, err = strconv.Atoi(ssid); err != nil {
+			return "", "", false
+		}
+	}
+	sym, ok := ssidSymbols[n]
+	if !ok {
+		return "", "", false
+	}
+	return sym[:1], sym[1:], true
+}
@@ -0,0 +1,54 @@
+package aprsutils
+
+import "testing"
+
+func TestSymbolToEmoji(t *testing.T) {
+	cases := []struct {
+		table, symbol, want string
+		ok                  bool
+	}{
+		{"/", ">", "🚗", true},
+		{"/", "b", "🚲", true},
+		{"/", "-", "🏠", true},
+		{"/", "_", "🌦️", true},
+		{"/", "^", "✈️", true},
+		{"\\", "s", "🚢", true},
+		{"D", "^", "✈️", true}, // overlay selects the alternate table
+		{"/", "#", "", false},
+		{"", ">", "", false},
+	}
+	for _, c := range cases {
+		got, ok := SymbolToEmoji(c.table, c.symbol)
+		if got != c.want || ok != c.ok {
+			t.Errorf("SymbolToEmoji(%q, %q) = %q, %v; want %q, %v", c.table, c.symbol, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestSymbolForSSID(t *testing.T) {
+	cases := []struct {
+		ssid, table, symbol string
+		ok                  bool
+	}{
+		{"9", "/", ">", true},
+		{"-9", "/", ">", true},
+		{"-7", "/", "[", true},
+		{"8", "/", "s", true},
+		{"10", "\\", "&", true},
+		{"11", "/", "O", true},
+		{"13", "/", "_", true},
+		{"14", "/", "k", true},
+		{"0", "/", "-", true},
+		{"", "/", "-", true},
+		{"1", "", "", false},
+		{"15", "", "", false},
+		{"16", "", "", false},
+		{"X", "", "", false},
+	}
+	for _, c := range cases {
+		table, symbol, ok := SymbolForSSID(c.ssid)
+		if table != c.table || symbol != c.symbol || ok != c.ok {
+			t.Errorf("SymbolForSSID(%q) = %q, %q, %v; want %q, %q, %v", c.ssid, table, symbol, ok, c.table, c.symbol, c.ok)
+		}
+	}
+}